@@ -1,26 +1,34 @@
 package crawler
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/PuerkitoBio/goquery"
 	"github.com/darthchudi/crwl/fetcher"
 	"github.com/darthchudi/crwl/graph"
 	"github.com/darthchudi/crwl/page"
+	"github.com/darthchudi/crwl/ratelimit"
+	"github.com/darthchudi/crwl/robots"
 	"github.com/darthchudi/crwl/stats"
+	"github.com/darthchudi/crwl/urlnorm"
 	"io"
+	netUrl "net/url"
 	"os"
-	"strings"
 	"sync"
 	"time"
 )
 
 type Crawler struct {
+	// dispatched is the number of pages handed to the workers to be fetched. It is updated
+	// atomically, so it comes first to be 64-bit aligned on 32-bit platforms
+	dispatched int64
+
 	// Starting URL to crawl
 	URL string
 
-	// Fetcher fetches pages a URL and returns the page body
-	Fetcher fetcher.Fetcher
+	// Fetcher fetches pages a URL and returns the response.
+	// Use `fetcher.Adapt` to crawl with a `fetcher.Fetcher`
+	Fetcher fetcher.ResponseFetcher
 
 	// Workers is numbers of workers to be created in the worker queue
 	Workers int
@@ -35,8 +43,58 @@ type Crawler struct {
 	// Stats provides statistical data about crawler operations
 	Stats *stats.Stats
 
+	// Limits bounds how much of the site is crawled.
+	// The crawl is unbounded by default
+	Limits Limits
+
+	// Normalization configures how discovered URLs are normalized,
+	// so the same page isn't crawled under different URLs
+	Normalization urlnorm.Options
+
+	// UserAgent is the user-agent whose robots.txt rules the crawler follows.
+	// Set to `fetcher.DefaultUserAgent` by default
+	UserAgent string
+
+	// IgnoreRobots disables robots.txt checks so every URL is fetched.
+	// Robots.txt Crawl-delay rules are also ignored
+	IgnoreRobots bool
+
+	// RetryPolicy decides which failed fetches are retried.
+	// Set to `fetcher.DefaultRetryPolicy()` by default
+	RetryPolicy fetcher.RetryPolicy
+
+	// RateLimiter limits the rate of requests made to each host.
+	// By default requests are only limited by the Workers count,
+	// robots.txt Crawl-delay rules and Retry-After headers
+	RateLimiter *ratelimit.Limiter
+
+	// LinkRules are the rules URLs are extracted from pages with.
+	// Set to `page.DefaultRules` by default
+	LinkRules []page.Rule
+
+	// IgnoreAssets disables checks of the assets pages load e.g images,
+	// scripts and stylesheets, so only pages are fetched
+	IgnoreAssets bool
+
+	// CheckExternalAssets enables checks of assets that are served from
+	// other domains. Only the crawler's own assets are checked by default
+	CheckExternalAssets bool
+
+	// robots caches the robots.txt rules of the hosts the crawler visits
+	robots *robots.Cache
+
+	// logWriter wraps the LogWriter so it can be written to by every crawler goroutine
+	logWriter io.Writer
+
+	// cancel stops the crawl's pipeline
+	cancel context.CancelFunc
+
+	// limitReached is the last limit that prevented a discovered URL from being queued.
+	// It is only written by the coordinator goroutine
+	limitReached string
+
 	// errors is a channel through which we receive errors on crawler operations
-	errors chan error
+	errors chan *Error
 
 	// wg is used to sync goroutines
 	wg *sync.WaitGroup
@@ -47,147 +105,482 @@ type Crawler struct {
 // at the same time.
 // A request timeout specifies the timeout for HTTP requests to fetch pages
 func NewCrawler(url string, workers int, timeout time.Duration) *Crawler {
-	// Remove trailing slash in the url
-	if strings.HasSuffix(url, "/") {
-		url = url[:len(url)-1]
+	// Normalize the url so it matches the links found on its pages
+	if normalized, err := urlnorm.Normalize(url, urlnorm.Options{}); err == nil {
+		url = normalized
 	}
 
 	httpFetcher := fetcher.NewHTTPFetcher(timeout)
 
 	return &Crawler{
-		URL:       url,
-		Fetcher:   httpFetcher,
-		LogWriter: os.Stdout,
-		Workers:   workers,
-		Graph:     graph.NewGraph(),
-		Stats:     stats.NewStats(),
-		errors:    make(chan error),
-		wg:        new(sync.WaitGroup),
+		URL:         url,
+		Fetcher:     httpFetcher,
+		LogWriter:   os.Stdout,
+		Workers:     workers,
+		Graph:       graph.NewGraph(),
+		Stats:       stats.NewStats(),
+		UserAgent:   fetcher.DefaultUserAgent,
+		RetryPolicy: fetcher.DefaultRetryPolicy(),
+		RateLimiter: ratelimit.NewLimiter(0, 1),
+		errors:      make(chan *Error),
+		wg:          new(sync.WaitGroup),
 	}
 }
 
+// task is a URL which is queued to be fetched by the workers
+type task struct {
+	// url is the URL to be fetched
+	url string
+
+	// depth is the number of clicks it takes to get to the URL from
+	// the crawler's starting URL
+	depth int
+
+	// asset is true if the URL is an asset loaded by a page. Assets are
+	// checked for errors, but their bodies are not parsed
+	asset bool
+}
+
 // listenForURLs creates a new channel for sending urls.
 // It starts n Goroutines (according to the concurrency limit)
 // that listen for URLs on the URL channel
 // When a new url is received on the channel, the HTML page which the link
 // points to is fetched and sent to the parser channel.
 //
+// Once the context is cancelled, URLs received on the channel are abandoned
+// without being fetched and in-flight fetches are cancelled.
+// The parser channel is closed when the URL channel has been closed and
+// every worker has exited.
+//
 // A send-only channel for sending URLs is returned (URL Channel) and a
 // receive-only channel for getting pages to be parsed (parserChannel)
-func (c *Crawler) listenForURLs() (chan<- string, <-chan page.RawPage) {
-	urlChannel := make(chan string)
+func (c *Crawler) listenForURLs(ctx context.Context) (chan<- task, <-chan page.RawPage) {
+	urlChannel := make(chan task)
 	parserChannel := make(chan page.RawPage)
 
+	workers := new(sync.WaitGroup)
+	workers.Add(c.Workers)
+
 	for i := 1; i <= c.Workers; i++ {
 		go func() {
-			for url := range urlChannel {
-				rawHTMlBody, err := c.Fetcher.Fetch(url)
+			defer workers.Done()
+
+			for t := range urlChannel {
+				if ctx.Err() != nil {
+					c.abandonUnfetched()
+					continue
+				}
+
+				url := t.url
+				response, err := c.fetch(ctx, url, t.asset)
+
+				// The crawl was stopped while the page was being fetched
+				if ctx.Err() != nil {
+					c.abandon()
+					continue
+				}
+
+				c.recordFetch(url, response, err)
+
+				if err != nil && t.asset {
+					c.Graph.SetStatus(url, graph.StatusBroken)
+					c.Stats.RecordBrokenAsset()
+					c.errors <- c.fetchError(url, true, err)
+					continue
+				}
 
 				if err != nil {
-					httpError := fmt.Errorf("failed to fetch %v: %v", url, err)
-					c.errors <- httpError
+					var loopError *fetcher.RedirectLoopError
+
+					if errors.As(err, &loopError) {
+						c.addRedirects(url, loopError.Chain[1:], t.depth)
+					}
+
+					c.Graph.SetStatus(url, graph.StatusFailed)
+					c.errors <- c.fetchError(url, false, err)
+					continue
+				}
+
+				if c.bytesLimitReached() {
+					c.stop(StopReasonMaxBytes)
+				}
+
+				// Assets are only checked, so they are complete once they have been fetched
+				if t.asset {
+					c.Stats.RecordOperationCompletion()
+					c.wg.Done()
 					continue
 				}
 
 				rawPage := page.RawPage{
-					URL:  url,
-					Body: rawHTMlBody,
+					URL:      url,
+					Depth:    t.depth,
+					Body:     response.Body,
+					Response: response,
 				}
 
 				// Send the raw page body to the parser channel
-				parserChannel <- rawPage
+				select {
+				case parserChannel <- rawPage:
+				case <-ctx.Done():
+					c.abandon()
+				}
 			}
 		}()
 	}
 
+	go func() {
+		workers.Wait()
+		close(parserChannel)
+	}()
+
 	return urlChannel, parserChannel
 }
 
 // startParser begins goroutines that read raw pages (in bytes),
 // parses and evaluates them and returns a receive only channel for
-// getting page results
-func (c *Crawler) startParser(parserChannel <-chan page.RawPage) chan page.Page {
+// getting page results.
+// The page channel is closed once the parser channel has been closed and
+// every page has been parsed.
+func (c *Crawler) startParser(ctx context.Context, parserChannel <-chan page.RawPage) <-chan page.Page {
 	pageChannel := make(chan page.Page)
 
 	go func() {
+		parsers := new(sync.WaitGroup)
+
 		for rawPage := range parserChannel {
+			parsers.Add(1)
+
 			go func(rawPage page.RawPage) {
-				document, err := goquery.NewDocumentFromReader(bytes.NewReader(rawPage.Body))
+				defer parsers.Done()
+
+				start := time.Now()
+				newPage, err := page.Parse(c.URL, rawPage, c.pageOptions())
+				c.Stats.RecordPhase(rawPage.URL, stats.PhaseParse, time.Since(start))
 
 				if err != nil {
-					c.errors <- err
+					c.errors <- c.newError(CategoryParse, rawPage.URL, err)
 					return
 				}
 
-				newPage := page.NewPage(c.URL, rawPage.URL, document)
-
 				// Send processed page to the page channel
-				pageChannel <- newPage
+				select {
+				case pageChannel <- newPage:
+				case <-ctx.Done():
+					c.abandon()
+				}
 			}(rawPage)
 		}
+
+		parsers.Wait()
+		close(pageChannel)
 	}()
 
 	return pageChannel
 }
 
-// listenForPages gets fetched pages and queues urls that haven't been visited in the page to be fetched
-func (c *Crawler) listenForPages(pageChannel <-chan page.Page, urlChannel chan<- string) {
+// listenForPages gets fetched pages and queues urls that haven't been visited in the page to be fetched.
+// No new URLs are queued once the context is cancelled or a crawl limit has been reached.
+// The returned channel is closed when the page channel has been drained.
+func (c *Crawler) listenForPages(ctx context.Context, pageChannel <-chan page.Page, urlChannel chan<- task) <-chan struct{} {
+	done := make(chan struct{})
+
 	// Start a single goroutine that acts as the coordinator by processing pages (results)
 	// and dispatching new URLs to be fetched from the pages.
 	go func() {
+		// unfetched holds discovered URLs that were not queued because of a crawl limit.
+		// A URL that was too deep when it was first discovered is queued if it is found
+		// again at a depth within the limit.
+		unfetched := make(map[string]struct{})
+
+		defer func() {
+			c.Stats.RecordUnfetched(int64(len(unfetched)))
+			close(done)
+		}()
+
 		for p := range pageChannel {
+			depth := p.Depth + 1
+
+			// The page's content belongs to the URL its redirects ended on
+			c.Graph.SetStatus(p.URL, graph.StatusFetched)
+			source := c.addRedirects(p.URL, p.Redirects, p.Depth)
+			c.Graph.SetStatus(source, graph.StatusFetched)
+			c.Graph.SetTitle(source, p.Title)
+			c.Graph.SetNoIndex(source, p.NoIndex)
+
+			if p.CanonicalURL != "" {
+				c.Graph.SetCanonical(source, p.CanonicalURL)
+			}
+
 			for _, url := range p.InternalURLs {
 
-				visited := c.Graph.HasNode(url)
+				_, skipped := unfetched[url]
+				visited := c.Graph.HasNode(url) && !skipped
 
 				if visited {
-					c.Graph.AddEdge(p.URL, url)
 					continue
 				}
 
-				c.Graph.AddNode(url)
-				c.Graph.AddEdge(p.URL, url)
-				c.wg.Add(1)
-				c.Stats.RecordNewOperation()
-				go func(u string) { urlChannel <- u }(url) // Send url to workers in a new goroutine to prevent blocking if all workers are busy
+				// Respect nofollow pages and rel=nofollow links
+				if !p.Follow(url) {
+					continue
+				}
+
+				// Stop discovering new URLs once the crawl has been stopped
+				if ctx.Err() != nil {
+					continue
+				}
+
+				c.discover(url, source, depth)
+
+				if reason := c.skipReason(depth); reason != "" {
+					c.limitReached = reason
+					unfetched[url] = struct{}{}
+					continue
+				}
+
+				delete(unfetched, url)
+				c.queue(ctx, urlChannel, task{url: url, depth: depth})
 			}
 
-			p.Print(c.LogWriter)
+			c.checkAssets(ctx, source, p, urlChannel, depth)
+			c.addLinks(source, p)
+
+			p.Print(c.logWriter)
+			c.Stats.RecordPage()
 			c.Stats.RecordOperationCompletion()
 			c.wg.Done()
 		}
 	}()
+
+	return done
 }
 
-// listenForErrors listens for errors and decrements the wait group when an error occurs
-func (c *Crawler) listenForErrors() {
+// listenForErrors listens for errors and decrements the wait group when an error occurs.
+// The returned channel is closed when the crawler's error channel has been drained.
+func (c *Crawler) listenForErrors() <-chan struct{} {
+	done := make(chan struct{})
+
 	go func() {
+		defer close(done)
+
 		for err := range c.errors {
-			fmt.Fprintf(c.LogWriter, "🥞 %v\n", err)
+			fmt.Fprintf(c.logWriter, "🥞 %v\n", err)
 
+			c.Stats.RecordFailure(err.failure())
 			c.Stats.RecordOperationFailure()
 			c.wg.Done()
 		}
 	}()
+
+	return done
+}
+
+// checkAssets queues the assets loaded by a page that haven't been checked yet.
+// Assets are not bound by the crawler's depth and page limits, since they are
+// part of a page that has already been fetched
+func (c *Crawler) checkAssets(ctx context.Context, source string, p page.Page, urlChannel chan<- task, depth int) {
+	if c.IgnoreAssets {
+		return
+	}
+
+	for _, url := range p.Assets() {
+		if !c.CheckExternalAssets && !p.IsInternal(url) {
+			continue
+		}
+
+		if c.Graph.HasNode(url) {
+			continue
+		}
+
+		// Stop discovering new URLs once the crawl has been stopped
+		if ctx.Err() != nil {
+			continue
+		}
+
+		c.discover(url, source, depth)
+		c.Stats.RecordAsset()
+		c.queue(ctx, urlChannel, task{url: url, depth: depth, asset: true})
+	}
 }
 
-// Crawl begins crawling the crawler's URL
-func (c *Crawler) Crawl() {
-	urlChannel, parserChannel := c.listenForURLs()
+// discover adds a URL found on a page with a particular URL to the graph, recording the
+// page it was first found on, and records the URL's depth
+func (c *Crawler) discover(url, fromURL string, depth int) {
+	if !c.Graph.HasNode(url) {
+		c.Graph.AddNode(url)
+		c.Graph.SetDiscoveredFrom(url, fromURL)
+	}
 
-	pageChannel := c.startParser(parserChannel)
+	c.Graph.SetDepth(url, depth)
+}
 
-	c.listenForPages(pageChannel, urlChannel)
+// addLinks adds an edge to the graph from a source URL for every link on a page to a URL
+// in the graph, and for the page's canonical URL if it is in the graph
+func (c *Crawler) addLinks(source string, p page.Page) {
+	for _, link := range p.Links {
+		if c.Graph.HasNode(link.URL) {
+			c.Graph.AddLink(source, link)
+		}
+	}
 
-	c.listenForErrors()
+	if p.CanonicalURL != "" && p.CanonicalURL != source && c.Graph.HasNode(p.CanonicalURL) {
+		c.Graph.AddLink(source, page.Link{URL: p.CanonicalURL, Kind: page.KindCanonical})
+	}
+}
 
-	// Start crawling by sending the crawler URL to the URL channel
-	c.Graph.AddNode(c.URL)
+// addRedirects adds the URLs a URL redirected to to the graph, along with an
+// edge for each redirect, and returns the URL the redirects ended on.
+// URLs that redirect to themselves once normalized e.g to add a trailing slash are not recorded
+func (c *Crawler) addRedirects(url string, redirects []string, depth int) string {
+	from, err := urlnorm.Normalize(url, c.Normalization)
+
+	if err != nil {
+		return url
+	}
+
+	for _, redirect := range redirects {
+		to, err := urlnorm.Normalize(redirect, c.Normalization)
+
+		if err != nil || to == from {
+			continue
+		}
+
+		if !c.Graph.HasNode(to) {
+			c.discover(to, from, depth)
+		}
+
+		c.Graph.AddLink(from, page.Link{URL: to, Kind: page.KindRedirect})
+		from = to
+	}
+
+	return from
+}
+
+// pageOptions returns the options pages are parsed with
+func (c *Crawler) pageOptions() page.Options {
+	return page.Options{Normalization: c.Normalization, UserAgent: c.UserAgent, Rules: c.LinkRules}
+}
+
+// hostOf returns the host of a URL, or the URL itself if it can't be parsed
+func hostOf(url string) string {
+	parsedURL, err := netUrl.Parse(url)
+
+	if err != nil {
+		return url
+	}
+
+	return parsedURL.Host
+}
+
+// queue records a new crawler operation for a URL and sends it to the workers once
+// robots.txt allows it to be fetched.
+// If the context is cancelled before a worker picks up the URL, the operation is abandoned.
+func (c *Crawler) queue(ctx context.Context, urlChannel chan<- task, t task) {
 	c.wg.Add(1)
 	c.Stats.RecordNewOperation()
-	urlChannel <- c.URL
 
+	// Send url to workers in a new goroutine to prevent blocking if all workers are busy
+	// or the host's robots.txt has to be fetched
+	go func() {
+		if !c.allowedByRobots(ctx, t.url) {
+			c.disallow(t.url)
+			return
+		}
+
+		// Pages queued before the limit was reached can still go over it
+		if !t.asset && !c.dispatchPage() {
+			c.Stats.RecordStopReason(StopReasonMaxPages)
+			c.abandonUnfetched()
+			return
+		}
+
+		select {
+		case urlChannel <- t:
+		case <-ctx.Done():
+			c.abandonUnfetched()
+		}
+	}()
+}
+
+// abandon marks an operation as dropped because the crawl was stopped before it completed
+func (c *Crawler) abandon() {
+	c.Stats.RecordOperationAbandoned()
+	c.wg.Done()
+}
+
+// abandonUnfetched marks an operation as dropped because the crawl was stopped
+// before its URL was fetched
+func (c *Crawler) abandonUnfetched() {
+	c.Stats.RecordUnfetched(1)
+	c.abandon()
+}
+
+// Crawl begins crawling the crawler's URL and blocks until every discovered
+// URL has been processed or the context is cancelled.
+//
+// When the context is cancelled or one of the crawler's limits is reached, no
+// new URLs are handed out to the workers and pending operations are abandoned.
+// In every case the crawler's goroutines are shut down before Crawl returns,
+// and the crawler's Graph and Stats hold the result of the work that was done.
+// Crawl only returns an error if the context was cancelled.
+// A Crawler can only crawl once.
+func (c *Crawler) Crawl(parent context.Context) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	c.cancel = cancel
+
+	// Normalize the url again in case the crawler's normalization options have changed
+	if normalized, err := urlnorm.Normalize(c.URL, c.Normalization); err == nil {
+		c.URL = normalized
+	}
+
+	c.Graph.Seed = c.URL
+	c.Graph.Normalization = c.Normalization
+	c.logWriter = &lockedWriter{w: c.LogWriter}
+	c.robots = robots.NewCache(politeFetcher{crawler: c})
+
+	if c.Limits.MaxDuration > 0 {
+		timer := time.AfterFunc(c.Limits.MaxDuration, func() { c.stop(StopReasonMaxDuration) })
+		defer timer.Stop()
+	}
+
+	urlChannel, parserChannel := c.listenForURLs(ctx)
+
+	pageChannel := c.startParser(ctx, parserChannel)
+
+	pagesDone := c.listenForPages(ctx, pageChannel, urlChannel)
+
+	errorsDone := c.listenForErrors()
+
+	// Start crawling by sending the crawler URL to the URL channel
 	c.Stats.RecordStartTime()
+	c.Graph.AddNode(c.URL)
+	c.queue(ctx, urlChannel, task{url: c.URL, depth: 0})
+
 	c.wg.Wait()
+
+	// Every operation has either completed, failed or been abandoned, so nothing
+	// else will be sent to the workers. Closing the URL channel shuts down the
+	// workers, which in turn closes the parser and page channels.
+	close(urlChannel)
+	<-pagesDone
+
+	// The workers and parsers are the only senders on the error channel
+	close(c.errors)
+	<-errorsDone
+
+	// A limit that ended the crawl early has already been recorded
+	if parent.Err() != nil {
+		c.Stats.RecordStopReason(StopReasonCancelled)
+	}
+
+	if c.limitReached != "" {
+		c.Stats.RecordStopReason(c.limitReached)
+	}
+
 	c.Stats.RecordTotalDuration()
+
+	return parent.Err()
 }
@@ -2,15 +2,29 @@ package crawler
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/darthchudi/crwl/fetcher"
+	"github.com/darthchudi/crwl/graph"
+	"github.com/darthchudi/crwl/page"
+	"github.com/darthchudi/crwl/stats"
+	"net"
+	"net/http"
+	netUrl "net/url"
+	"os"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
 
 func TestCrawl(t *testing.T) {
 	crawler := NewCrawler("https://example.com", 10, time.Second*20)
-	crawler.Fetcher = MockFetcher{}
-	crawler.Crawl()
+	crawler.Fetcher = fetcher.Adapt(robotsFetcher{})
+	crawler.Crawl(context.Background())
 
 	// We expect that the crawler visits only known links
 	expectedCompleted := int64(len(mockFetcherCache))
@@ -18,17 +32,40 @@ func TestCrawl(t *testing.T) {
 		t.Errorf("expected crawler to have completed %v tasks, got %v", expectedCompleted, crawler.Stats.Completed())
 	}
 
+	// Every response is counted by its status code, along with how long it took.
+	// The request for the host's robots.txt isn't part of the crawl, so it isn't counted
+	if codes := crawler.Stats.StatusCodes(); codes[200] != expectedCompleted || crawler.Stats.Latency().Count() != expectedCompleted {
+		t.Errorf("expected %v responses to be recorded, got %v and %v latencies", expectedCompleted, codes, crawler.Stats.Latency().Count())
+	}
+
+	// Every page's parse time is recorded for its host
+	if hosts := crawler.Stats.HostTimings(); len(hosts) != 1 || hosts[0].Phases[0].Phase != stats.PhaseParse || hosts[0].Phases[0].Count != crawler.Stats.Pages() {
+		t.Errorf("expected the parse time of %v pages to be recorded, got %+v", crawler.Stats.Pages(), hosts)
+	}
+
 	// We expect that there will be no pending tasks by the time Crawl() returns
 	if crawler.Stats.Pending() != 0 {
 		t.Errorf("expected crawler to have 0 pending tasks, got %v", crawler.Stats.Pending())
 	}
 
+	// The graph records where the crawl started, so it can be saved with it
+	if crawler.Graph.Seed != "https://example.com" {
+		t.Errorf("expected the graph's seed to be https://example.com, got %v", crawler.Graph.Seed)
+	}
+
 	// Check that all known links are in the crawler's cache
 	for url := range mockFetcherCache {
 		if !crawler.Graph.HasNode(url) {
 			t.Errorf("expected crawler to have visited url %v", url)
 		}
 	}
+
+	// Links are stored in the graph with their anchor text
+	edges := crawler.Graph.LinksWithText("https://example.com/loans", "Loans")
+
+	if len(edges) != 3 || edges[0].From != "https://example.com" || edges[0].Link.Element != "a" {
+		t.Errorf("expected 3 pages to link to https://example.com/loans, got %+v", edges)
+	}
 }
 
 func TestCrawlError(t *testing.T) {
@@ -36,19 +73,23 @@ func TestCrawlError(t *testing.T) {
 	// Provide a URL that is not recognized by the mock fetcher
 	// This will cause fetch to fail and the crawler should record an error
 	crawler := NewCrawler("https://test.com", 10, time.Second*20)
-	crawler.Fetcher = MockFetcher{}
+	crawler.Fetcher = fetcher.Adapt(MockFetcher{})
 
 	// Use a mock log writer
 	logWriter := bytes.NewBuffer([]byte{})
 	crawler.LogWriter = logWriter
 
-	crawler.Crawl()
+	crawler.Crawl(context.Background())
 
 	expectedFailures := 1
 	if crawler.Stats.Failures() != int64(expectedFailures) {
 		t.Errorf("expected crawler to have failed %v task(s), got %v", expectedFailures, crawler.Stats.Failures())
 	}
 
+	if failed := crawler.Stats.FailedURLs(); len(failed) != 1 || failed[0].Category != string(CategoryOther) || failed[0].URL != "https://test.com" {
+		t.Errorf("expected https://test.com to fail in the %q category, got %+v", CategoryOther, failed)
+	}
+
 	// We expect that there will be no pending tasks by the time Crawl() returns
 	if crawler.Stats.Pending() != 0 {
 		t.Errorf("expected crawler to have 0 pending tasks, got %v", crawler.Stats.Pending())
@@ -62,3 +103,494 @@ func TestCrawlError(t *testing.T) {
 		t.Fatalf("expected error message to contain %v, got %v", expected, errorMessage)
 	}
 }
+
+func TestCrawlCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the crawl while the starting URL is being fetched
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = fetcher.Adapt(cancellingFetcher{cancel: cancel})
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+
+	err := crawler.Crawl(ctx)
+
+	if err != context.Canceled {
+		t.Fatalf("expected crawl to return %v, got %v", context.Canceled, err)
+	}
+
+	if crawler.Stats.StopReason() != StopReasonCancelled {
+		t.Errorf("expected stop reason %q, got %q", StopReasonCancelled, crawler.Stats.StopReason())
+	}
+
+	// We expect that the in-flight fetch is abandoned and no new URLs are queued
+	if crawler.Stats.Abandoned() != 1 {
+		t.Errorf("expected crawler to have abandoned 1 task, got %v", crawler.Stats.Abandoned())
+	}
+
+	if crawler.Stats.Completed() != 0 {
+		t.Errorf("expected crawler to have completed 0 tasks, got %v", crawler.Stats.Completed())
+	}
+
+	if crawler.Stats.Pending() != 0 {
+		t.Errorf("expected crawler to have 0 pending tasks, got %v", crawler.Stats.Pending())
+	}
+}
+
+func TestCrawlLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		limits            Limits
+		expectedReason    string
+		expectedCompleted int64
+		expectedUnfetched int64
+	}{
+		{name: "max depth", limits: Limits{MaxDepth: 1}, expectedReason: StopReasonMaxDepth, expectedCompleted: 3, expectedUnfetched: 1},
+		{name: "max pages", limits: Limits{MaxPages: 1}, expectedReason: StopReasonMaxPages, expectedCompleted: 1, expectedUnfetched: 2},
+		{name: "no limits", limits: Limits{}, expectedReason: "", expectedCompleted: 4, expectedUnfetched: 0},
+	}
+
+	for _, tc := range tests {
+		crawler := NewCrawler("https://example.com", 10, time.Second*20)
+		crawler.Fetcher = fetcher.Adapt(MockFetcher{})
+		crawler.LogWriter = bytes.NewBuffer([]byte{})
+		crawler.Limits = tc.limits
+
+		err := crawler.Crawl(context.Background())
+
+		if err != nil {
+			t.Fatalf("%v: expected crawl to succeed, got %v", tc.name, err)
+		}
+
+		if crawler.Stats.StopReason() != tc.expectedReason {
+			t.Errorf("%v: expected stop reason %q, got %q", tc.name, tc.expectedReason, crawler.Stats.StopReason())
+		}
+
+		if crawler.Stats.Completed() != tc.expectedCompleted {
+			t.Errorf("%v: expected crawler to have completed %v tasks, got %v", tc.name, tc.expectedCompleted, crawler.Stats.Completed())
+		}
+
+		if crawler.Stats.Unfetched() != tc.expectedUnfetched {
+			t.Errorf("%v: expected crawler to have %v unfetched URLs, got %v", tc.name, tc.expectedUnfetched, crawler.Stats.Unfetched())
+		}
+
+		if crawler.Stats.Pending() != 0 {
+			t.Errorf("%v: expected crawler to have 0 pending tasks, got %v", tc.name, crawler.Stats.Pending())
+		}
+	}
+}
+
+func TestCrawlMaxPagesDisallowed(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = fetcher.Adapt(robotsFetcher{robots: "User-agent: crwl\nDisallow: /shared-tabs$"})
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+	crawler.Limits = Limits{MaxPages: 3}
+
+	crawler.Crawl(context.Background())
+
+	// URLs disallowed by robots.txt aren't fetched, so they don't count towards the limit
+	if crawler.Stats.Pages() != 3 || crawler.Stats.Disallowed() != 1 {
+		t.Errorf("expected crawler to have fetched 3 pages with 1 disallowed, got %v and %v", crawler.Stats.Pages(), crawler.Stats.Disallowed())
+	}
+
+	if crawler.Stats.StopReason() != "" || crawler.Stats.Unfetched() != 0 {
+		t.Errorf("expected every allowed page to be fetched, got stop reason %q and %v unfetched URLs", crawler.Stats.StopReason(), crawler.Stats.Unfetched())
+	}
+}
+
+func TestCrawlMaxBytes(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = fetcher.Adapt(MockFetcher{})
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+	crawler.Limits = Limits{MaxBytes: 1}
+
+	crawler.Crawl(context.Background())
+
+	if crawler.Stats.StopReason() != StopReasonMaxBytes {
+		t.Errorf("expected stop reason %q, got %q", StopReasonMaxBytes, crawler.Stats.StopReason())
+	}
+
+	// We expect that no URLs are queued after the first page crosses the limit
+	if crawler.Stats.Total() != 1 {
+		t.Errorf("expected crawler to have processed 1 URL, got %v", crawler.Stats.Total())
+	}
+
+	if crawler.Stats.Pending() != 0 {
+		t.Errorf("expected crawler to have 0 pending tasks, got %v", crawler.Stats.Pending())
+	}
+}
+
+func TestCrawlMaxDuration(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = fetcher.Adapt(slowFetcher{delay: time.Millisecond * 100})
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+	crawler.Limits = Limits{MaxDuration: time.Millisecond * 10}
+
+	err := crawler.Crawl(context.Background())
+
+	// Reaching a limit is not an error
+	if err != nil {
+		t.Fatalf("expected crawl to succeed, got %v", err)
+	}
+
+	if crawler.Stats.StopReason() != StopReasonMaxDuration {
+		t.Errorf("expected stop reason %q, got %q", StopReasonMaxDuration, crawler.Stats.StopReason())
+	}
+
+	if crawler.Stats.Pending() != 0 {
+		t.Errorf("expected crawler to have 0 pending tasks, got %v", crawler.Stats.Pending())
+	}
+}
+
+func TestCrawlRobots(t *testing.T) {
+	tests := []struct {
+		ignoreRobots       bool
+		expectedCompleted  int64
+		expectedDisallowed int64
+	}{
+		{ignoreRobots: false, expectedCompleted: 3, expectedDisallowed: 1},
+		{ignoreRobots: true, expectedCompleted: 4, expectedDisallowed: 0},
+	}
+
+	disallowedURL := "https://example.com/shared-tabs"
+
+	for _, tc := range tests {
+		crawler := NewCrawler("https://example.com", 10, time.Second*20)
+		crawler.Fetcher = fetcher.Adapt(robotsFetcher{robots: "User-agent: crwl\nDisallow: /shared-tabs$"})
+		crawler.LogWriter = bytes.NewBuffer([]byte{})
+		crawler.IgnoreRobots = tc.ignoreRobots
+
+		crawler.Crawl(context.Background())
+
+		if crawler.Stats.Completed() != tc.expectedCompleted {
+			t.Errorf("expected crawler to have completed %v tasks, got %v", tc.expectedCompleted, crawler.Stats.Completed())
+		}
+
+		if crawler.Stats.Disallowed() != tc.expectedDisallowed {
+			t.Errorf("expected crawler to have %v disallowed URLs, got %v", tc.expectedDisallowed, crawler.Stats.Disallowed())
+		}
+
+		// Disallowed URLs are listed with the failures
+		if categories := crawler.Stats.FailuresByCategory(); categories[string(CategoryDisallowed)] != tc.expectedDisallowed {
+			t.Errorf("expected %v failures in the %q category, got %v", tc.expectedDisallowed, CategoryDisallowed, categories)
+		}
+
+		if crawler.Stats.Pending() != 0 {
+			t.Errorf("expected crawler to have 0 pending tasks, got %v", crawler.Stats.Pending())
+		}
+
+		// Disallowed URLs are recorded in the graph
+		status, _ := crawler.Graph.Status(disallowedURL)
+		disallowed := status == graph.StatusDisallowed
+
+		if disallowed != !tc.ignoreRobots {
+			t.Errorf("expected %v to be recorded as disallowed: %v, got status %q", disallowedURL, !tc.ignoreRobots, status)
+		}
+	}
+}
+
+func TestCrawlRobotsRetries(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	robots := &flakyRobotsFetcher{robotsFetcher: robotsFetcher{robots: "User-agent: crwl\nDisallow: /shared-tabs$"}, failures: 1}
+	crawler.Fetcher = fetcher.Adapt(robots)
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+	crawler.RetryPolicy = fetcher.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryOn: fetcher.RetryAll}
+
+	crawler.Crawl(context.Background())
+
+	// robots.txt is retried like any other URL, so its first 503 doesn't disallow the whole site
+	if crawler.Stats.Completed() != 3 || crawler.Stats.Disallowed() != 1 {
+		t.Errorf("expected crawler to have completed 3 tasks with 1 disallowed, got %v and %v", crawler.Stats.Completed(), crawler.Stats.Disallowed())
+	}
+
+	if robots.attempts != 2 {
+		t.Errorf("expected crawler to have fetched robots.txt twice, got %v attempts", robots.attempts)
+	}
+
+	// robots.txt isn't part of the crawl, so its retry isn't counted
+	if crawler.Stats.Retries() != 0 {
+		t.Errorf("expected crawler to have no retries, got %v", crawler.Stats.Retries())
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = fetcher.Adapt(robotsFetcher{robots: "User-agent: *\nCrawl-delay: 0.02"})
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+
+	crawler.Crawl(context.Background())
+
+	// We expect the crawler to wait for the crawl delay between each of the 4 pages
+	expected := time.Millisecond * 60
+	if crawler.Stats.Duration() < expected {
+		t.Errorf("expected crawl to take at least %v, took %v", expected, crawler.Stats.Duration())
+	}
+
+	if crawler.Stats.Completed() != 4 {
+		t.Errorf("expected crawler to have completed 4 tasks, got %v", crawler.Stats.Completed())
+	}
+}
+
+func TestCrawlRetryAfter(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = fetcher.Adapt(statusFetcher{err: &fetcher.StatusError{StatusCode: 429, RetryAfter: time.Millisecond * 100}})
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+	crawler.RetryPolicy = fetcher.RetryPolicy{MaxAttempts: 1}
+
+	crawler.Crawl(context.Background())
+
+	if crawler.Stats.Failures() != 1 {
+		t.Errorf("expected crawler to have failed 1 task, got %v", crawler.Stats.Failures())
+	}
+
+	// We expect the crawler to have paused requests to the host
+	start := time.Now()
+	crawler.RateLimiter.Wait(context.Background(), "example.com")
+
+	if time.Since(start) < time.Millisecond*50 {
+		t.Errorf("expected requests to example.com to be paused, waited %v", time.Since(start))
+	}
+}
+
+func TestCrawlRetries(t *testing.T) {
+	tests := []struct {
+		failures          int
+		expectedCompleted int64
+		expectedFailures  int64
+		expectedRetries   int64
+		expectedFlaky     int64
+	}{
+		// Every URL fails once and is fetched on its second attempt
+		{failures: 1, expectedCompleted: 4, expectedFailures: 0, expectedRetries: 4, expectedFlaky: 4},
+		// The starting URL fails every attempt
+		{failures: 3, expectedCompleted: 0, expectedFailures: 1, expectedRetries: 2, expectedFlaky: 0},
+	}
+
+	for _, tc := range tests {
+		logWriter := bytes.NewBuffer([]byte{})
+
+		crawler := NewCrawler("https://example.com", 10, time.Second*20)
+		crawler.Fetcher = fetcher.Adapt(&flakyFetcher{failures: tc.failures, attempts: map[string]int{}})
+		crawler.LogWriter = logWriter
+		crawler.RetryPolicy = fetcher.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryOn: fetcher.RetryAll}
+
+		// A robots.txt that fails with a 503 disallows the whole site
+		crawler.IgnoreRobots = true
+
+		crawler.Crawl(context.Background())
+
+		if crawler.Stats.Completed() != tc.expectedCompleted {
+			t.Errorf("expected crawler to have completed %v tasks, got %v", tc.expectedCompleted, crawler.Stats.Completed())
+		}
+
+		if crawler.Stats.Failures() != tc.expectedFailures {
+			t.Errorf("expected crawler to have failed %v tasks, got %v", tc.expectedFailures, crawler.Stats.Failures())
+		}
+
+		if crawler.Stats.Retries() != tc.expectedRetries {
+			t.Errorf("expected crawler to have retried %v times, got %v", tc.expectedRetries, crawler.Stats.Retries())
+		}
+
+		if crawler.Stats.Flaky() != tc.expectedFlaky {
+			t.Errorf("expected crawler to have %v flaky URLs, got %v", tc.expectedFlaky, crawler.Stats.Flaky())
+		}
+
+		if tc.expectedFailures > 0 && !strings.Contains(logWriter.String(), "after 3 attempts") {
+			t.Errorf("expected failure to include the number of attempts, got %v", logWriter.String())
+		}
+	}
+}
+
+func TestCrawlNoFollow(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = headerFetcher{header: http.Header{"X-Robots-Tag": []string{"nofollow"}}}
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+
+	crawler.Crawl(context.Background())
+
+	// We expect the crawler not to follow the links on the starting page
+	if crawler.Stats.Completed() != 1 {
+		t.Errorf("expected crawler to have completed 1 task, got %v", crawler.Stats.Completed())
+	}
+
+	if crawler.Graph.HasNode("https://example.com/loans") {
+		t.Errorf("expected crawler not to have discovered https://example.com/loans")
+	}
+}
+
+func TestCrawlNoIndex(t *testing.T) {
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = &assetFetcher{bodies: map[string]string{
+		"https://example.com":         `<a href="/about">About</a>`,
+		"https://example.com/about":   `<meta name="robots" content="noindex"><a href="/contact">Contact</a>`,
+		"https://example.com/contact": `<a href="/">Home</a>`,
+	}}
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+
+	crawler.Crawl(context.Background())
+
+	// noindex pages are crawled and their links followed, but they are recorded as noindex in the graph
+	if crawler.Stats.Completed() != 3 {
+		t.Errorf("expected crawler to have completed 3 tasks, got %v", crawler.Stats.Completed())
+	}
+
+	for url, expected := range map[string]bool{"https://example.com": false, "https://example.com/about": true, "https://example.com/contact": false} {
+		if node, _ := crawler.Graph.Node(url); node.NoIndex() != expected {
+			t.Errorf("expected %v to be recorded as noindex: %v", url, expected)
+		}
+	}
+}
+
+func TestCrawlAssets(t *testing.T) {
+	assets := &assetFetcher{bodies: map[string]string{
+		"https://example.com":               `<a href="/about">About</a><img src="/logo.png"><script src="/app.js"></script><img src="https://cdn.example.net/photo.jpg">`,
+		"https://example.com/about":         `<a href="/">Home</a><img src="/logo.png">`,
+		"https://example.com/logo.png":      "png",
+		"https://example.com/robots.txt":    "",
+		"https://cdn.example.net/photo.jpg": "jpg",
+	}}
+
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = assets
+	crawler.RetryPolicy = fetcher.RetryPolicy{MaxAttempts: 1}
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+
+	crawler.Crawl(context.Background())
+
+	// The two pages and the logo are fetched, while the missing script fails
+	if crawler.Stats.Completed() != 3 || crawler.Stats.Failures() != 1 {
+		t.Errorf("expected crawler to have completed 3 tasks and failed 1, got %v and %v", crawler.Stats.Completed(), crawler.Stats.Failures())
+	}
+
+	if crawler.Stats.Assets() != 2 || crawler.Stats.BrokenAssets() != 1 {
+		t.Errorf("expected crawler to have checked 2 assets with 1 broken, got %v and %v", crawler.Stats.Assets(), crawler.Stats.BrokenAssets())
+	}
+
+	if status, _ := crawler.Graph.Status("https://example.com/app.js"); status != graph.StatusBroken {
+		t.Errorf("expected https://example.com/app.js to be broken, got %q", status)
+	}
+
+	expectedFailure := stats.Failure{Category: string(CategoryClientError), URL: "https://example.com/app.js", Referrer: "https://example.com", StatusCode: 404}
+
+	if failed := crawler.Stats.FailedURLs(); len(failed) != 1 || failed[0] != expectedFailure {
+		t.Errorf("expected failure %+v, got %+v", expectedFailure, failed)
+	}
+
+	// Assets are checked with HEAD requests, and external assets are skipped
+	if len(assets.heads) != 2 {
+		t.Errorf("expected 2 HEAD requests, got %v", assets.heads)
+	}
+
+	if crawler.Graph.HasNode("https://cdn.example.net/photo.jpg") {
+		t.Errorf("expected crawler not to have checked the external asset")
+	}
+
+	// Fetches are recorded on the graph's nodes
+	tests := []struct {
+		url            string
+		statusCode     int
+		size           int64
+		discoveredFrom string
+	}{
+		{url: "https://example.com", statusCode: 200, size: int64(len(assets.bodies["https://example.com"]))},
+		{url: "https://example.com/about", statusCode: 200, size: int64(len(assets.bodies["https://example.com/about"])), discoveredFrom: "https://example.com"},
+		{url: "https://example.com/logo.png", statusCode: 200, size: 3, discoveredFrom: "https://example.com"},
+		{url: "https://example.com/app.js", statusCode: 404, discoveredFrom: "https://example.com"},
+	}
+
+	for _, tc := range tests {
+		node, _ := crawler.Graph.Node(tc.url)
+
+		if node.StatusCode() != tc.statusCode || node.Size() != tc.size || node.DiscoveredFrom() != tc.discoveredFrom {
+			t.Errorf("expected %v to have status code %v, size %v and been discovered from %q, got %v, %v and %q",
+				tc.url, tc.statusCode, tc.size, tc.discoveredFrom, node.StatusCode(), node.Size(), node.DiscoveredFrom())
+		}
+
+		if node.FetchedAt().IsZero() || node.DiscoveredAt().After(node.FetchedAt()) {
+			t.Errorf("expected %v to have been fetched after it was discovered", tc.url)
+		}
+	}
+}
+
+func TestCrawlRedirects(t *testing.T) {
+	redirects := &redirectFetcher{
+		assetFetcher: assetFetcher{bodies: map[string]string{
+			"https://example.com":            `<a href="/old">Old</a><a href="/loop">Loop</a><a href="/print">Print</a>`,
+			"https://example.com/new":        `<link rel="canonical" href="/new"><a href="/">Home</a>`,
+			"https://example.com/print":      `<link rel="canonical" href="/">`,
+			"https://example.com/robots.txt": "",
+		}},
+		redirects: map[string]string{
+			"https://example.com/old":   "https://example.com/new",
+			"https://example.com/loop":  "https://example.com/loop2",
+			"https://example.com/loop2": "https://example.com/loop",
+		},
+	}
+
+	crawler := NewCrawler("https://example.com", 10, time.Second*20)
+	crawler.Fetcher = redirects
+	crawler.RetryPolicy = fetcher.RetryPolicy{MaxAttempts: 1}
+	crawler.LogWriter = bytes.NewBuffer([]byte{})
+
+	crawler.Crawl(context.Background())
+
+	if crawler.Stats.Completed() != 3 || crawler.Stats.Failures() != 1 {
+		t.Errorf("expected crawler to have completed 3 tasks and failed 1, got %v and %v", crawler.Stats.Completed(), crawler.Stats.Failures())
+	}
+
+	// Redirects are recorded in the graph
+	edges := crawler.Graph.Outlinks("https://example.com/old")
+
+	if len(edges) != 1 || edges[0].To != "https://example.com/new" || edges[0].Link.Kind != page.KindRedirect {
+		t.Errorf("expected https://example.com/old to redirect to https://example.com/new, got %+v", edges)
+	}
+
+	if status, _ := crawler.Graph.Status("https://example.com/old"); status != graph.StatusFetched {
+		t.Errorf("expected https://example.com/old to have been fetched, got %q", status)
+	}
+
+	loops := crawler.Graph.RedirectLoops()
+
+	if len(loops) != 1 || strings.Join(loops[0], " ") != "https://example.com/loop https://example.com/loop2" {
+		t.Errorf("expected a redirect loop between the loop pages, got %v", loops)
+	}
+
+	if status, _ := crawler.Graph.Status("https://example.com/loop"); status != graph.StatusFailed {
+		t.Errorf("expected https://example.com/loop to have failed, got %q", status)
+	}
+
+	// Canonical URLs are recorded as edges, unless a page is its own canonical page
+	canonicals := crawler.Graph.EdgesOfKind(graph.KindCanonical)
+
+	if len(canonicals) != 1 || canonicals[0].From != "https://example.com/print" || canonicals[0].To != "https://example.com" {
+		t.Errorf("expected https://example.com/print to have a canonical edge to https://example.com, got %+v", canonicals)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected Category
+	}{
+		{name: "dns", err: &netUrl.Error{Op: "Get", URL: "https://example.com", Err: &net.DNSError{Err: "no such host", Name: "example.com"}}, expected: CategoryDNS},
+		{name: "refused", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, expected: CategoryConnectionRefused},
+		{name: "reset", err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, expected: CategoryConnectionReset},
+		{name: "timeout", err: context.DeadlineExceeded, expected: CategoryTimeout},
+		{name: "tls", err: &netUrl.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, expected: CategoryTLS},
+		{name: "tls record header", err: &netUrl.Error{Op: "Get", URL: "https://example.com", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}}, expected: CategoryTLS},
+		{name: "tls alert", err: &netUrl.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "remote error", Err: errors.New("tls: protocol version not supported")}}, expected: CategoryTLS},
+		{name: "tls in message", err: fmt.Errorf("parse page: tls: not a handshake"), expected: CategoryOther},
+		{name: "4xx", err: &fetcher.StatusError{StatusCode: 404}, expected: CategoryClientError},
+		{name: "5xx after retries", err: &fetcher.RetryError{Attempts: []fetcher.Attempt{{Err: &fetcher.StatusError{StatusCode: 503}}}}, expected: CategoryServerError},
+		{name: "body too large", err: fmt.Errorf("%w: more than 10 bytes", fetcher.ErrBodyTooLarge), expected: CategoryBodyTooLarge},
+		{name: "redirect loop", err: &fetcher.RedirectLoopError{Chain: []string{"https://example.com", "https://example.com"}}, expected: CategoryRedirectLoop},
+		{name: "other", err: errors.New("something went wrong"), expected: CategoryOther},
+	}
+
+	for _, tc := range tests {
+		if category := categorize(tc.err); category != tc.expected {
+			t.Errorf("%v: expected category %q, got %q", tc.name, tc.expected, category)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/darthchudi/crwl/fetcher"
+	"github.com/darthchudi/crwl/stats"
+	"net"
+	"syscall"
+)
+
+// Category describes why a URL failed
+type Category string
+
+const (
+	// CategoryDNS means the address of the URL's host couldn't be looked up
+	CategoryDNS Category = "dns"
+
+	// CategoryConnectionRefused means the URL's host refused the connection
+	CategoryConnectionRefused Category = "connection_refused"
+
+	// CategoryConnectionReset means the URL's host reset or closed the
+	// connection before sending a complete response
+	CategoryConnectionReset Category = "connection_reset"
+
+	// CategoryTimeout means the request for the URL timed out
+	CategoryTimeout Category = "timeout"
+
+	// CategoryTLS means the TLS handshake with the URL's host failed e.g
+	// because its certificate is invalid
+	CategoryTLS Category = "tls"
+
+	// CategoryClientError means the URL was served with a 4xx status code
+	CategoryClientError Category = "http_4xx"
+
+	// CategoryServerError means the URL was served with a 5xx status code
+	CategoryServerError Category = "http_5xx"
+
+	// CategoryBodyTooLarge means the URL's response was larger than the fetcher's maximum body size
+	CategoryBodyTooLarge Category = "body_too_large"
+
+	// CategoryRedirectLoop means the URL's redirects led back to a URL that was already requested
+	CategoryRedirectLoop Category = "redirect_loop"
+
+	// CategoryParse means the URL's page couldn't be parsed
+	CategoryParse Category = "parse"
+
+	// CategoryDisallowed means the URL wasn't fetched because robots.txt disallows it
+	CategoryDisallowed Category = "disallowed"
+
+	// CategoryOther means the URL failed for any other reason
+	CategoryOther Category = "other"
+)
+
+// Error is an error that occurred while crawling a URL
+type Error struct {
+	// Category describes why the URL failed
+	Category Category
+
+	// URL is the URL that failed
+	URL string
+
+	// Referrer is the URL of the page the URL was first found on.
+	// It is empty for the crawler's starting URL
+	Referrer string
+
+	// StatusCode is the HTTP status code the URL was last served with, or 0 if there was no response
+	StatusCode int
+
+	// Asset is true if the URL is an asset loaded by a page
+	Asset bool
+
+	// Err is the underlying error
+	Err error
+}
+
+func (e *Error) Error() string {
+	var message string
+
+	switch {
+	case e.Category == CategoryParse:
+		message = fmt.Sprintf("failed to parse %v: %v", e.URL, e.Err)
+	case e.Asset:
+		message = fmt.Sprintf("broken asset %v: %v", e.URL, e.Err)
+	default:
+		message = fmt.Sprintf("failed to fetch %v: %v", e.URL, e.Err)
+	}
+
+	if e.Referrer != "" {
+		message += fmt.Sprintf(" (found on %v)", e.Referrer)
+	}
+
+	return message
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// failure returns the error's record in the crawler's stats
+func (e *Error) failure() stats.Failure {
+	return stats.Failure{Category: string(e.Category), URL: e.URL, Referrer: e.Referrer, StatusCode: e.StatusCode}
+}
+
+// newError returns an Error of a category for a URL, referred by the page it was first found on
+func (c *Crawler) newError(category Category, url string, err error) *Error {
+	node, _ := c.Graph.Node(url)
+
+	return &Error{Category: category, URL: url, Referrer: node.DiscoveredFrom(), Err: err}
+}
+
+// fetchError returns an Error for a URL that couldn't be fetched
+func (c *Crawler) fetchError(url string, asset bool, err error) *Error {
+	e := c.newError(categorize(err), url, err)
+	e.Asset = asset
+
+	var statusError *fetcher.StatusError
+
+	if errors.As(err, &statusError) {
+		e.StatusCode = statusError.StatusCode
+	}
+
+	return e
+}
+
+// categorize returns the category of the error a URL couldn't be fetched with
+func categorize(err error) Category {
+	var statusError *fetcher.StatusError
+	var loopError *fetcher.RedirectLoopError
+	var dnsError *net.DNSError
+
+	switch {
+	case errors.As(err, &statusError) && statusError.StatusCode >= 500:
+		return CategoryServerError
+	case errors.As(err, &statusError) && statusError.StatusCode >= 400:
+		return CategoryClientError
+	case errors.As(err, &loopError):
+		return CategoryRedirectLoop
+	case errors.Is(err, fetcher.ErrBodyTooLarge):
+		return CategoryBodyTooLarge
+	case errors.As(err, &dnsError):
+		return CategoryDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return CategoryConnectionRefused
+	case isTLSError(err):
+		return CategoryTLS
+	case fetcher.IsTimeout(err):
+		return CategoryTimeout
+	case fetcher.IsConnectionReset(err):
+		return CategoryConnectionReset
+	}
+
+	return CategoryOther
+}
+
+// isTLSError checks if an error is caused by a failed TLS handshake
+func isTLSError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var recordHeader tls.RecordHeaderError
+
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) || errors.As(err, &recordHeader) {
+		return true
+	}
+
+	// Alerts sent or received during a handshake, such as protocol version mismatches,
+	// are wrapped by crypto/tls in a net.OpError whose operation is only used for TLS alerts
+	var opError *net.OpError
+
+	return errors.As(err, &opError) && (opError.Op == "remote error" || opError.Op == "local error")
+}
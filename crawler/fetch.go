@@ -0,0 +1,190 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"github.com/darthchudi/crwl/fetcher"
+	"github.com/darthchudi/crwl/graph"
+	"github.com/darthchudi/crwl/stats"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// fetch fetches a URL, retrying failed attempts according to the crawler's retry policy.
+// Every attempt waits for the URL's host to be available on the rate limiter.
+// Assets are checked with HEAD requests when the crawler's fetcher supports them.
+//
+// A *fetcher.RetryError holding every failed attempt is returned if the URL
+// couldn't be fetched, and the context's error if the crawl was stopped.
+func (c *Crawler) fetch(ctx context.Context, url string, asset bool) (*fetcher.Response, error) {
+	return c.retry(ctx, url, asset, true)
+}
+
+// retry fetches a URL like fetch. Requests, responses and retries are only recorded
+// in the crawler's stats if recorded is true, so URLs that aren't part of the crawl,
+// like robots.txt files, don't count towards its pages, bytes and timings
+func (c *Crawler) retry(ctx context.Context, url string, asset, recorded bool) (*fetcher.Response, error) {
+	host := hostOf(url)
+	attempts := []fetcher.Attempt{}
+
+	for {
+		// Wait until we can make a request to the URL's host without being impolite
+		if err := c.RateLimiter.Wait(ctx, host); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+
+		if recorded {
+			c.Stats.RecordRequestStart()
+		}
+
+		response, err := c.request(ctx, url, asset)
+
+		if recorded {
+			c.Stats.RecordRequestEnd()
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		attempt := fetcher.Attempt{Err: err, Duration: time.Since(start)}
+
+		if err != nil && recorded {
+			c.Stats.RecordLatency(attempt.Duration)
+		}
+
+		if err == nil && recorded {
+			c.Stats.RecordResponse(response.StatusCode, attempt.Duration)
+			c.recordTiming(url, response.Timing)
+			c.Stats.RecordBytes(int64(len(response.Body)))
+		}
+
+		if err == nil {
+			// Back off from hosts that ask us to slow down
+			if response.RetryAfter > 0 {
+				c.RateLimiter.Pause(host, response.RetryAfter)
+			}
+
+			if response.Successful() && recorded {
+				if response.Redirected() {
+					c.Stats.RecordRedirect()
+				}
+
+				// The URL is flaky if it was fetched after failing before
+				if len(attempts) > 0 {
+					c.Stats.RecordFlaky()
+				}
+			}
+
+			if response.Successful() {
+				return response, nil
+			}
+
+			attempt.StatusCode = response.StatusCode
+			attempt.Err = response.StatusError()
+		}
+
+		attempts = append(attempts, attempt)
+
+		if len(attempts) >= c.RetryPolicy.MaxAttempts || !c.RetryPolicy.Retryable(response, err) {
+			return nil, &fetcher.RetryError{URL: url, Attempts: attempts}
+		}
+
+		delay := c.RetryPolicy.Backoff(len(attempts))
+		attempts[len(attempts)-1].Delay = delay
+
+		if recorded {
+			c.Stats.RecordRetry()
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// request makes a single request for a URL. Assets are requested with a HEAD
+// request if the crawler's fetcher supports it, falling back to a GET request
+// for servers that don't allow HEAD requests
+func (c *Crawler) request(ctx context.Context, url string, asset bool) (*fetcher.Response, error) {
+	if headFetcher, ok := c.Fetcher.(fetcher.HeadFetcher); ok && asset {
+		response, err := headFetcher.Head(ctx, url)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if response.StatusCode != http.StatusMethodNotAllowed && response.StatusCode != http.StatusNotImplemented {
+			return response, nil
+		}
+	}
+
+	return c.Fetcher.FetchResponse(ctx, url)
+}
+
+// recordFetch records the outcome of fetching a URL on its node in the graph.
+// The status code and latency of the last attempt are recorded for URLs that couldn't be fetched
+func (c *Crawler) recordFetch(url string, response *fetcher.Response, err error) {
+	fetch := graph.Fetch{FetchedAt: time.Now()}
+
+	var retryError *fetcher.RetryError
+
+	switch {
+	case err == nil:
+		fetch.StatusCode = response.StatusCode
+		fetch.ContentType = response.ContentType
+		fetch.Size = responseSize(response)
+		fetch.Latency = response.Duration
+	case errors.As(err, &retryError):
+		last := retryError.Attempts[len(retryError.Attempts)-1]
+		fetch.StatusCode = last.StatusCode
+		fetch.Latency = last.Duration
+	}
+
+	c.Graph.SetFetch(url, fetch)
+}
+
+// recordTiming records how long each phase of a request took in the crawler's stats.
+// Phases that didn't happen, such as connecting over a reused connection, are left out
+func (c *Crawler) recordTiming(url string, timing fetcher.Timing) {
+	phases := []struct {
+		phase    stats.Phase
+		duration time.Duration
+	}{
+		{phase: stats.PhaseDNS, duration: timing.DNS},
+		{phase: stats.PhaseConnect, duration: timing.Connect},
+		{phase: stats.PhaseTLS, duration: timing.TLS},
+		{phase: stats.PhaseFirstByte, duration: timing.FirstByte},
+		{phase: stats.PhaseDownload, duration: timing.Download},
+	}
+
+	for _, p := range phases {
+		if p.duration > 0 {
+			c.Stats.RecordPhase(url, p.phase, p.duration)
+		}
+	}
+}
+
+// responseSize returns the size of a response's body. The Content-Length header
+// is used for responses to HEAD requests, which have no body
+func responseSize(response *fetcher.Response) int64 {
+	if len(response.Body) > 0 {
+		return int64(len(response.Body))
+	}
+
+	size, err := strconv.ParseInt(response.Header.Get("Content-Length"), 10, 64)
+
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
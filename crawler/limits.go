@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Reasons recorded in the crawler's stats when a crawl ends before every
+// discovered URL has been fetched
+const (
+	// StopReasonMaxDepth means URLs deeper than the crawler's MaxDepth were not fetched
+	StopReasonMaxDepth = "max depth"
+
+	// StopReasonMaxPages means the crawler fetched its MaxPages limit of pages
+	StopReasonMaxPages = "max pages"
+
+	// StopReasonMaxBytes means the crawler downloaded its MaxBytes limit of bytes
+	StopReasonMaxBytes = "max bytes"
+
+	// StopReasonMaxDuration means the crawl ran for its MaxDuration limit
+	StopReasonMaxDuration = "max duration"
+
+	// StopReasonCancelled means the context passed to Crawl was cancelled
+	StopReasonCancelled = "cancelled"
+)
+
+// Limits bounds how much of a site the crawler fetches.
+// A zero value for any limit means there is no limit.
+type Limits struct {
+	// MaxDepth is the maximum number of clicks from the starting URL
+	// that a page can be at to be fetched
+	MaxDepth int
+
+	// MaxPages is the maximum number of pages to fetch. Asset checks and
+	// URLs disallowed by robots.txt don't count towards the limit
+	MaxPages int64
+
+	// MaxBytes is the maximum number of bytes to download. The page that
+	// crosses the limit is processed, after which the crawl is stopped
+	MaxBytes int64
+
+	// MaxDuration is the maximum amount of time the crawl can run for
+	MaxDuration time.Duration
+}
+
+// skipReason returns the limit that prevents a URL at a given depth from
+// being queued, or an empty string if the URL can be queued
+func (c *Crawler) skipReason(depth int) string {
+	if c.Limits.MaxDepth > 0 && depth > c.Limits.MaxDepth {
+		return StopReasonMaxDepth
+	}
+
+	if c.Limits.MaxPages > 0 && atomic.LoadInt64(&c.dispatched) >= c.Limits.MaxPages {
+		return StopReasonMaxPages
+	}
+
+	return ""
+}
+
+// dispatchPage counts a page that is about to be handed to the workers, and checks
+// that fetching it keeps the crawler within its MaxPages limit
+func (c *Crawler) dispatchPage() bool {
+	dispatched := atomic.AddInt64(&c.dispatched, 1)
+
+	return c.Limits.MaxPages == 0 || dispatched <= c.Limits.MaxPages
+}
+
+// bytesLimitReached checks if the crawler has downloaded as many bytes as it is allowed to
+func (c *Crawler) bytesLimitReached() bool {
+	return c.Limits.MaxBytes > 0 && c.Stats.Bytes() >= c.Limits.MaxBytes
+}
+
+// stop ends the crawl early because of a reason.
+// Only the first reason a crawl is stopped for is recorded.
+func (c *Crawler) stop(reason string) {
+	c.Stats.RecordStopReason(reason)
+	c.cancel()
+}
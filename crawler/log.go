@@ -0,0 +1,20 @@
+package crawler
+
+import (
+	"io"
+	"sync"
+)
+
+// lockedWriter serializes writes to a writer that is shared by the crawler's goroutines
+type lockedWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// Write writes to the underlying writer while holding the lock
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Write(p)
+}
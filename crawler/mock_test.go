@@ -3,16 +3,24 @@ package crawler
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"github.com/darthchudi/crwl/fetcher"
 	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // fetcherCache is a map of mock urls and the path to their equivalent
 // mock html file
 var mockFetcherCache = map[string]string{
-	"https://example.com":             "index.html",
-	"https://example.com/loans":       "loans.html",
-	"https://example.com/shared-tabs": "shared-tabs.html",
+	"https://example.com":                "index.html",
+	"https://example.com/loans":          "loans.html",
+	"https://example.com/shared-tabs":    "shared-tabs.html",
+	"https://example.com/loans/personal": "personal-loans.html",
 }
 
 // MockFetcher is a mock fetcher that fetches URLs from an
@@ -49,3 +57,220 @@ func (f MockFetcher) getHTMLPage(path string) ([]byte, error) {
 
 	return buff.Bytes(), nil
 }
+
+// cancellingFetcher is a mock fetcher that cancels a crawl
+// whenever it fetches a URL. This allows us to stop a crawl
+// while a fetch is in-flight within tests
+type cancellingFetcher struct {
+	MockFetcher
+
+	cancel context.CancelFunc
+}
+
+// Fetch cancels the crawl and fetches a URL from the internal fetcher cache
+func (f cancellingFetcher) Fetch(url string) ([]byte, error) {
+	f.cancel()
+
+	return f.MockFetcher.Fetch(url)
+}
+
+// slowFetcher is a mock fetcher that waits for a delay
+// before fetching a URL from the internal fetcher cache
+type slowFetcher struct {
+	MockFetcher
+
+	delay time.Duration
+}
+
+// Fetch waits for the fetcher's delay and fetches a URL from the internal fetcher cache
+func (f slowFetcher) Fetch(url string) ([]byte, error) {
+	time.Sleep(f.delay)
+
+	return f.MockFetcher.Fetch(url)
+}
+
+// robotsFetcher is a mock fetcher that serves a robots.txt
+// file and fetches other URLs from the internal fetcher cache
+type robotsFetcher struct {
+	MockFetcher
+
+	robots string
+}
+
+// Fetch returns the fetcher's robots.txt file for robots.txt URLs
+// and fetches other URLs from the internal fetcher cache
+func (f robotsFetcher) Fetch(url string) ([]byte, error) {
+	if strings.HasSuffix(url, "/robots.txt") {
+		return []byte(f.robots), nil
+	}
+
+	return f.MockFetcher.Fetch(url)
+}
+
+// flakyRobotsFetcher is a mock fetcher that fails the first attempts
+// to fetch robots.txt with a 503 error, and serves its robots.txt file after that
+type flakyRobotsFetcher struct {
+	robotsFetcher
+
+	// failures is the number of attempts to fetch robots.txt to fail
+	failures int
+
+	attempts int
+	mu       sync.Mutex
+}
+
+// Fetch fails or serves robots.txt, and fetches other URLs from the internal fetcher cache
+func (f *flakyRobotsFetcher) Fetch(url string) ([]byte, error) {
+	if strings.HasSuffix(url, "/robots.txt") {
+		f.mu.Lock()
+		f.attempts++
+		attempts := f.attempts
+		f.mu.Unlock()
+
+		if attempts <= f.failures {
+			return nil, &fetcher.StatusError{StatusCode: 503}
+		}
+	}
+
+	return f.robotsFetcher.Fetch(url)
+}
+
+// statusFetcher is a mock fetcher that fails every
+// request with a HTTP status error
+type statusFetcher struct {
+	err *fetcher.StatusError
+}
+
+// Fetch returns the fetcher's status error
+func (f statusFetcher) Fetch(url string) ([]byte, error) {
+	return nil, f.err
+}
+
+// flakyFetcher is a mock fetcher that fails the first
+// attempts to fetch each URL with a 503 error, and fetches
+// URLs from the internal fetcher cache after that
+type flakyFetcher struct {
+	MockFetcher
+
+	// failures is the number of attempts to fail for each URL
+	failures int
+
+	attempts map[string]int
+	mu       sync.Mutex
+}
+
+// Fetch fails or fetches a URL from the internal fetcher cache
+func (f *flakyFetcher) Fetch(url string) ([]byte, error) {
+	f.mu.Lock()
+	f.attempts[url]++
+	attempts := f.attempts[url]
+	f.mu.Unlock()
+
+	if attempts <= f.failures {
+		return nil, &fetcher.StatusError{StatusCode: 503}
+	}
+
+	return f.MockFetcher.Fetch(url)
+}
+
+// headerFetcher is a mock response fetcher that fetches URLs from
+// the internal fetcher cache and adds headers to their responses
+type headerFetcher struct {
+	header http.Header
+}
+
+// FetchResponse fetches a URL from the internal fetcher cache and adds the fetcher's headers to the response
+func (f headerFetcher) FetchResponse(ctx context.Context, url string) (*fetcher.Response, error) {
+	response, err := fetcher.Adapt(MockFetcher{}).FetchResponse(ctx, url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Header = f.header
+	return response, nil
+}
+
+// assetFetcher is a mock response fetcher that serves canned
+// bodies and supports HEAD requests. URLs without a body
+// respond with a 404 error
+type assetFetcher struct {
+	bodies map[string]string
+
+	// heads are the URLs that were requested with a HEAD request
+	heads []string
+	mu    sync.Mutex
+}
+
+// FetchResponse returns the fetcher's body for a URL
+func (f *assetFetcher) FetchResponse(ctx context.Context, url string) (*fetcher.Response, error) {
+	response := &fetcher.Response{URL: url, FinalURL: url, StatusCode: http.StatusNotFound, Header: http.Header{}}
+
+	if body, exists := f.bodies[url]; exists {
+		response.StatusCode = http.StatusOK
+		response.ContentType = "text/html"
+		response.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		response.Body = []byte(body)
+	}
+
+	return response, nil
+}
+
+// Head records a HEAD request and returns the response for a URL without its body
+func (f *assetFetcher) Head(ctx context.Context, url string) (*fetcher.Response, error) {
+	f.mu.Lock()
+	f.heads = append(f.heads, url)
+	f.mu.Unlock()
+
+	response, err := f.FetchResponse(ctx, url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = nil
+	return response, nil
+}
+
+// redirectFetcher is a mock response fetcher that serves canned bodies like
+// an assetFetcher, and redirects URLs to other URLs
+type redirectFetcher struct {
+	assetFetcher
+
+	// redirects maps URLs to the URLs they redirect to
+	redirects map[string]string
+}
+
+// FetchResponse follows the redirects of a URL and returns the body of the URL it ends on.
+// A redirect loop error is returned if the redirects loop
+func (f *redirectFetcher) FetchResponse(ctx context.Context, url string) (*fetcher.Response, error) {
+	chain := []string{url}
+	current := url
+
+	for {
+		next, redirected := f.redirects[current]
+
+		if !redirected {
+			break
+		}
+
+		for _, previous := range chain {
+			if previous == next {
+				return nil, &fetcher.RedirectLoopError{Chain: append(chain, next)}
+			}
+		}
+
+		chain = append(chain, next)
+		current = next
+	}
+
+	response, err := f.assetFetcher.FetchResponse(ctx, current)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.URL = url
+	response.Redirects = chain[1:]
+	return response, nil
+}
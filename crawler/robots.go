@@ -0,0 +1,80 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/darthchudi/crwl/fetcher"
+	"github.com/darthchudi/crwl/graph"
+	netUrl "net/url"
+)
+
+// allowedByRobots checks if the robots.txt of a URL's host allows the crawler to fetch the URL.
+// The host's Crawl-delay is applied to the crawler's rate limiter.
+// URLs are always allowed when the crawler ignores robots.txt.
+func (c *Crawler) allowedByRobots(ctx context.Context, url string) bool {
+	if c.IgnoreRobots {
+		return true
+	}
+
+	rules, err := c.robots.Get(ctx, url)
+
+	// URLs are abandoned once the crawl is stopped, so there is nothing to log
+	if err != nil && ctx.Err() != nil {
+		return true
+	}
+
+	if err != nil {
+		fmt.Fprintf(c.logWriter, "🤖 failed to check robots.txt for %v: %v\n", url, err)
+		return true
+	}
+
+	parsedURL, err := netUrl.Parse(url)
+
+	if err != nil {
+		fmt.Fprintf(c.logWriter, "🤖 failed to check robots.txt for %v: %v\n", url, err)
+		return true
+	}
+
+	if delay := rules.CrawlDelay(c.UserAgent); delay > 0 {
+		c.RateLimiter.SetDelay(parsedURL.Host, delay)
+	}
+
+	return rules.Allowed(c.UserAgent, parsedURL.RequestURI())
+}
+
+// politeFetcher fetches robots.txt files the way the crawler fetches pages, waiting for the
+// host on the rate limiter and retrying failed attempts according to the retry policy.
+// Unlike pages, robots.txt files are left out of the crawl's stats and limits
+type politeFetcher struct {
+	crawler *Crawler
+}
+
+// FetchResponse fetches a robots.txt file. Responses that failed with a status code are
+// returned without an error, so a missing robots.txt can be told apart from an unavailable one
+func (f politeFetcher) FetchResponse(ctx context.Context, url string) (*fetcher.Response, error) {
+	response, err := f.crawler.retry(ctx, url, false, false)
+
+	var retryError *fetcher.RetryError
+
+	if errors.As(err, &retryError) {
+		if last := retryError.Attempts[len(retryError.Attempts)-1]; last.StatusCode != 0 {
+			return &fetcher.Response{URL: url, FinalURL: url, StatusCode: last.StatusCode}, nil
+		}
+	}
+
+	return response, err
+}
+
+// errDisallowed is the error of URLs that robots.txt disallows
+var errDisallowed = errors.New("disallowed by robots.txt")
+
+// disallow marks an operation as dropped because robots.txt disallows its URL
+func (c *Crawler) disallow(url string) {
+	fmt.Fprintf(c.logWriter, "🤖 %v is disallowed by robots.txt\n", url)
+
+	c.Graph.SetStatus(url, graph.StatusDisallowed)
+	c.Stats.RecordFailure(c.newError(CategoryDisallowed, url, errDisallowed).failure())
+	c.Stats.RecordOperationDisallowed()
+	c.wg.Done()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runDiff compares two saved crawl graphs and prints what changed between them:
+//
+//	crwl diff yesterday.graph today.graph
+//
+// It exits with a non-zero status if the newer crawl lost URLs or has new broken links
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: crwl diff <old> <new>\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	older, err := loadGraph(fs.Arg(0))
+
+	if err != nil {
+		log.Fatalf("🥞 Failed to load %v: %v", fs.Arg(0), err)
+	}
+
+	newer, err := loadGraph(fs.Arg(1))
+
+	if err != nil {
+		log.Fatalf("🥞 Failed to load %v: %v", fs.Arg(1), err)
+	}
+
+	diff := older.Diff(newer)
+
+	if err := diff.Write(os.Stdout); err != nil {
+		log.Fatalf("🥞 Failed to write diff: %v", err)
+	}
+
+	if diff.Regressed() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/darthchudi/crwl/graph"
+	"log"
+	"os"
+)
+
+// runExport crawls a site and writes its graph in a format that can be visualized:
+//
+//	crwl export --url=https://example.com --format=gexf --output=example.gexf
+//
+// The graph is written to stdout if no output file is given, in which case
+// the crawl's logs are written to stderr
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	flags := newCrawlFlags(fs)
+	format := fs.String("format", graph.FormatDOT, fmt.Sprintf("Format to export the graph in: %v, %v or %v", graph.FormatDOT, graph.FormatGraphML, graph.FormatGEXF))
+	output := fs.String("output", "", "File to write the graph to (default: stdout)")
+
+	fs.Parse(args)
+
+	switch *format {
+	case graph.FormatDOT, graph.FormatGraphML, graph.FormatGEXF:
+	default:
+		log.Fatalf("🥞 Invalid --format flag: %v", *format)
+	}
+
+	c := flags.crawler()
+	c.LogWriter = os.Stderr
+
+	flags.crawl(c, os.Stderr)
+	fmt.Fprintln(os.Stderr)
+
+	w := os.Stdout
+
+	if *output != "" {
+		file, err := os.Create(*output)
+
+		if err != nil {
+			log.Fatalf("🥞 Failed to create %v: %v", *output, err)
+		}
+
+		defer file.Close()
+		w = file
+	}
+
+	if err := c.Graph.Export(w, *format); err != nil {
+		log.Fatalf("🥞 Failed to export graph: %v", err)
+	}
+}
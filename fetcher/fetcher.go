@@ -1,10 +1,14 @@
 package fetcher
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
 	"time"
 )
 
@@ -15,27 +19,100 @@ type Fetcher interface {
 	Fetch(url string) ([]byte, error)
 }
 
+// ErrBodyTooLarge is returned when a response body is larger than a fetcher's maximum body size
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// StatusError is returned when a server responds with a status other than 200 OK
+type StatusError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// RetryAfter is how long the server asked us to wait before making
+	// another request. It is only set for 429 and 503 responses
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with http %v", e.StatusCode)
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is
+// either a number of seconds or a HTTP date.
+// It returns 0 if the value is invalid or in the past
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+
+	if err != nil {
+		return 0
+	}
+
+	if wait := time.Until(date); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
+
+// DefaultUserAgent is the user-agent crwl identifies itself with
+const DefaultUserAgent = "crwl"
+
 // HTTPFetcher fetches pages over HTTP using a custom "net/http" client
 type HTTPFetcher struct {
+	// UserAgent is sent in the User-Agent header of every request
+	UserAgent string
+
+	// MaxBodySize is the largest response body in bytes that is downloaded.
+	// Larger responses fail with ErrBodyTooLarge. Bodies of any size are downloaded if it is 0
+	MaxBodySize int64
+
 	client http.Client
 }
 
 // NewHTTPFetcher initializes a new HTTP Fetcher with a custom
 // HTTP client with a timeout
 func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
-	client := http.Client{Timeout: timeout}
+	client := http.Client{Timeout: timeout, CheckRedirect: checkRedirect}
 
-	return &HTTPFetcher{client: client}
+	return &HTTPFetcher{UserAgent: DefaultUserAgent, client: client}
 }
 
-// Fetch makes a HTTP request to fetch a URL and returns the URL page body
-func (h *HTTPFetcher) Fetch(url string) ([]byte, error) {
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+// FetchResponse makes a HTTP request to fetch a URL and returns the response.
+// Redirects are followed and the request is cancelled if the context is cancelled.
+func (h *HTTPFetcher) FetchResponse(ctx context.Context, url string) (*Response, error) {
+	return h.do(ctx, http.MethodGet, url)
+}
+
+// Head makes a HTTP HEAD request to a URL and returns the response without a body.
+// Redirects are followed and the request is cancelled if the context is cancelled.
+func (h *HTTPFetcher) Head(ctx context.Context, url string) (*Response, error) {
+	return h.do(ctx, http.MethodHead, url)
+}
+
+// do makes a HTTP request with a method to a URL and returns the response
+func (h *HTTPFetcher) do(ctx context.Context, method string, url string) (*Response, error) {
+	tracer := &tracer{}
+	ctx = httptrace.WithClientTrace(ctx, tracer.trace())
+
+	request, err := http.NewRequestWithContext(ctx, method, url, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if h.UserAgent != "" {
+		request.Header.Set("User-Agent", h.UserAgent)
+	}
+
+	start := time.Now()
+
 	response, err := h.client.Do(request)
 
 	if err != nil {
@@ -44,16 +121,54 @@ func (h *HTTPFetcher) Fetch(url string) ([]byte, error) {
 
 	defer response.Body.Close()
 
-	if response.StatusCode != http.StatusOK {
-		message := fmt.Sprintf("request failed with http %v", response.StatusCode)
-		return nil, errors.New(message)
+	body := io.Reader(response.Body)
+
+	// Read a byte past the limit, so bodies that exceed it can be told apart from bodies that match it
+	if h.MaxBodySize > 0 {
+		body = io.LimitReader(body, h.MaxBodySize+1)
+	}
+
+	pageBody, err := ioutil.ReadAll(body)
+
+	if err != nil {
+		return nil, err
 	}
 
-	pageBody, err := ioutil.ReadAll(response.Body)
+	if h.MaxBodySize > 0 && int64(len(pageBody)) > h.MaxBodySize {
+		return nil, fmt.Errorf("%w: more than %v bytes", ErrBodyTooLarge, h.MaxBodySize)
+	}
+
+	result := &Response{
+		URL:         url,
+		FinalURL:    response.Request.URL.String(),
+		Redirects:   redirects(response),
+		StatusCode:  response.StatusCode,
+		Header:      response.Header,
+		ContentType: mediaType(response.Header.Get("Content-Type")),
+		Body:        pageBody,
+		Duration:    time.Since(start),
+		Timing:      tracer.done(),
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		result.RetryAfter = parseRetryAfter(response.Header.Get("Retry-After"))
+	}
+
+	return result, nil
+}
+
+// Fetch makes a HTTP request to fetch a URL and returns the URL page body.
+// A StatusError is returned if the response status is not 200 OK.
+func (h *HTTPFetcher) Fetch(url string) ([]byte, error) {
+	response, err := h.FetchResponse(context.Background(), url)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return pageBody, nil
+	if response.StatusCode != http.StatusOK {
+		return nil, response.StatusError()
+	}
+
+	return response.Body, nil
 }
@@ -1,6 +1,11 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,3 +19,264 @@ func TestHTTPFetcher(t *testing.T) {
 		t.Fatalf("http fetcher error: %v", err)
 	}
 }
+
+func TestHTTPFetcherRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second * 10)
+
+	_, err := fetcher.Fetch(server.URL)
+
+	var statusError *StatusError
+
+	if !errors.As(err, &statusError) {
+		t.Fatalf("expected a status error, got %v", err)
+	}
+
+	if statusError.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected status code %v, got %v", http.StatusTooManyRequests, statusError.StatusCode)
+	}
+
+	if statusError.RetryAfter != time.Minute*2 {
+		t.Fatalf("expected retry after to be %v, got %v", time.Minute*2, statusError.RetryAfter)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{value: "30", min: time.Second * 30, max: time.Second * 30},
+		{value: "-1", min: 0, max: 0},
+		{value: "soon", min: 0, max: 0},
+		{value: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), min: time.Second * 58, max: time.Minute},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", min: 0, max: 0},
+	}
+
+	for _, tc := range tests {
+		got := parseRetryAfter(tc.value)
+
+		if got < tc.min || got > tc.max {
+			t.Errorf("expected retry after %q to be between %v and %v, got %v", tc.value, tc.min, tc.max, got)
+		}
+	}
+}
+
+func TestHTTPFetcherFetchResponse(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second * 10)
+
+	response, err := fetcher.FetchResponse(context.Background(), server.URL+"/old")
+
+	if err != nil {
+		t.Fatalf("http fetcher error: %v", err)
+	}
+
+	if !response.Redirected() || response.FinalURL != server.URL+"/new" {
+		t.Fatalf("expected response to be redirected to %v, got %v", server.URL+"/new", response.FinalURL)
+	}
+
+	if len(response.Redirects) != 1 || response.Redirects[0] != server.URL+"/new" {
+		t.Fatalf("expected response to have been redirected once, got %v", response.Redirects)
+	}
+
+	if response.StatusCode != http.StatusOK || response.ContentType != "text/html" {
+		t.Fatalf("expected a 200 text/html response, got %v %v", response.StatusCode, response.ContentType)
+	}
+
+	if string(response.Body) != "<html></html>" {
+		t.Fatalf("unexpected response body %q", response.Body)
+	}
+
+	// The test server is plain HTTP on an IP address, so there is no DNS lookup or TLS handshake
+	if timing := response.Timing; timing.Connect == 0 || timing.FirstByte == 0 || timing.DNS != 0 || timing.TLS != 0 {
+		t.Fatalf("unexpected response timing %+v", timing)
+	}
+
+	// Error statuses are returned as responses
+	response, err = fetcher.FetchResponse(context.Background(), server.URL+"/missing")
+
+	if err != nil {
+		t.Fatalf("http fetcher error: %v", err)
+	}
+
+	if response.StatusCode != http.StatusNotFound || response.Successful() {
+		t.Fatalf("expected an unsuccessful 404 response, got %v", response.StatusCode)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &tracer{}
+	trace := tracer.trace()
+
+	// Two of the host's addresses are dialed at once, and only one of them connects
+	trace.GetConn("example.com:443")
+	trace.ConnectStart("tcp", "[2001:db8::1]:443")
+	trace.ConnectStart("tcp", "192.0.2.1:443")
+	time.Sleep(10 * time.Millisecond)
+	trace.ConnectDone("tcp", "192.0.2.1:443", nil)
+	time.Sleep(40 * time.Millisecond)
+	trace.ConnectDone("tcp", "[2001:db8::1]:443", errors.New("connection refused"))
+
+	// The body of a redirect is read until the next request starts
+	trace.GotFirstResponseByte()
+	time.Sleep(10 * time.Millisecond)
+	trace.GetConn("example.com:443")
+	trace.GotFirstResponseByte()
+	time.Sleep(10 * time.Millisecond)
+
+	timing := tracer.done()
+
+	if timing.Connect < 10*time.Millisecond || timing.Connect >= 50*time.Millisecond {
+		t.Errorf("expected connect to only time the dial that succeeded, got %v", timing.Connect)
+	}
+
+	if timing.Download < 20*time.Millisecond {
+		t.Errorf("expected download to add up the body of every response, got %v", timing.Download)
+	}
+}
+
+func TestHTTPFetcherRedirectLoop(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err := NewHTTPFetcher(time.Second*10).FetchResponse(context.Background(), server.URL+"/a")
+
+	var loopError *RedirectLoopError
+
+	if !errors.As(err, &loopError) {
+		t.Fatalf("expected a redirect loop error, got %v", err)
+	}
+
+	expected := []string{server.URL + "/a", server.URL + "/b", server.URL + "/a"}
+
+	if strings.Join(loopError.Chain, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected redirect chain %v, got %v", expected, loopError.Chain)
+	}
+}
+
+func TestHTTPFetcherHead(t *testing.T) {
+	var method string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second * 10)
+
+	response, err := fetcher.Head(context.Background(), server.URL+"/logo.png")
+
+	if err != nil {
+		t.Fatalf("http fetcher error: %v", err)
+	}
+
+	if method != http.MethodHead {
+		t.Fatalf("expected a %v request, got %v", http.MethodHead, method)
+	}
+
+	if response.StatusCode != http.StatusOK || response.ContentType != "image/png" || len(response.Body) != 0 {
+		t.Fatalf("expected a 200 image/png response without a body, got %v %v %q", response.StatusCode, response.ContentType, response.Body)
+	}
+}
+
+func TestHTTPFetcherMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second * 10)
+	fetcher.MaxBodySize = 10
+
+	// Bodies as large as the limit are downloaded
+	if response, err := fetcher.FetchResponse(context.Background(), server.URL); err != nil || len(response.Body) != 10 {
+		t.Fatalf("expected a 10 byte body, got %v", err)
+	}
+
+	fetcher.MaxBodySize = 9
+
+	if _, err := fetcher.FetchResponse(context.Background(), server.URL); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("expected error %v, got %v", ErrBodyTooLarge, err)
+	}
+}
+
+// mockFetcher returns a canned body or error
+type mockFetcher struct {
+	body []byte
+	err  error
+}
+
+func (f mockFetcher) Fetch(url string) ([]byte, error) {
+	return f.body, f.err
+}
+
+func TestAdapt(t *testing.T) {
+	url := "https://example.com"
+
+	response, err := Adapt(mockFetcher{body: []byte("<html></html>")}).FetchResponse(context.Background(), url)
+
+	if err != nil {
+		t.Fatalf("adapter error: %v", err)
+	}
+
+	if response.StatusCode != http.StatusOK || response.ContentType != "text/html" || response.FinalURL != url {
+		t.Fatalf("expected a 200 text/html response from %v, got %v %v from %v", url, response.StatusCode, response.ContentType, response.FinalURL)
+	}
+
+	// Status errors are turned into responses
+	statusError := &StatusError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Second}
+	response, err = Adapt(mockFetcher{err: statusError}).FetchResponse(context.Background(), url)
+
+	if err != nil {
+		t.Fatalf("adapter error: %v", err)
+	}
+
+	if response.StatusCode != http.StatusServiceUnavailable || response.RetryAfter != time.Second {
+		t.Fatalf("expected a 503 response with a retry after, got %v %v", response.StatusCode, response.RetryAfter)
+	}
+
+	// Other errors are returned as they are
+	_, err = Adapt(mockFetcher{err: errors.New("connection refused")}).FetchResponse(context.Background(), url)
+
+	if err == nil {
+		t.Fatalf("expected adapter to return an error")
+	}
+
+	// Response fetchers are not wrapped
+	httpFetcher := NewHTTPFetcher(time.Second)
+
+	if Adapt(httpFetcher) != ResponseFetcher(httpFetcher) {
+		t.Fatalf("expected adapter to return the http fetcher")
+	}
+}
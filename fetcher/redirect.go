@@ -0,0 +1,58 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// maxRedirects is the maximum number of redirects followed for a request
+const maxRedirects = 10
+
+// RedirectLoopError is returned when following the redirects of a URL leads
+// back to a URL that was already requested
+type RedirectLoopError struct {
+	// Chain holds the URLs that were requested in order, ending with
+	// the URL that was requested again
+	Chain []string
+}
+
+func (e *RedirectLoopError) Error() string {
+	return "redirect loop: " + strings.Join(e.Chain, " -> ")
+}
+
+// checkRedirect is the redirect policy of the HTTP fetcher's client.
+// It stops following redirects once they loop or after 10 redirects
+func checkRedirect(request *http.Request, via []*http.Request) error {
+	for _, previous := range via {
+		if previous.URL.String() != request.URL.String() {
+			continue
+		}
+
+		chain := []string{}
+
+		for _, r := range via {
+			chain = append(chain, r.URL.String())
+		}
+
+		return &RedirectLoopError{Chain: append(chain, request.URL.String())}
+	}
+
+	if len(via) >= maxRedirects {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	return nil
+}
+
+// redirects returns the URLs a response was redirected to in order,
+// ending with the URL it was served from
+func redirects(response *http.Response) []string {
+	chain := []string{}
+
+	for request := response.Request; request != nil && request.Response != nil; request = request.Response.Request {
+		chain = append([]string{request.URL.String()}, chain...)
+	}
+
+	return chain
+}
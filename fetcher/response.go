@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Response is the result of fetching a URL
+type Response struct {
+	// URL is the URL that was requested
+	URL string
+
+	// FinalURL is the URL the response was served from after following redirects
+	FinalURL string
+
+	// Redirects are the URLs the request was redirected to in order, ending
+	// with the FinalURL. It is empty if the request wasn't redirected
+	Redirects []string
+
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Header holds the response headers
+	Header http.Header
+
+	// ContentType is the media type of the response body without
+	// its parameters e.g "text/html"
+	ContentType string
+
+	// Body is the response body
+	Body []byte
+
+	// RetryAfter is how long the server asked us to wait before making
+	// another request. It is only set for 429 and 503 responses
+	RetryAfter time.Duration
+
+	// Duration is how long it took to fetch the URL
+	Duration time.Duration
+
+	// Timing breaks down how long it took to fetch the URL into its phases.
+	// It is only recorded by fetchers that can trace their requests
+	Timing Timing
+}
+
+// Successful checks if the response has a 2xx status code
+func (r *Response) Successful() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Redirected checks if the response was served from a different URL than the one requested
+func (r *Response) Redirected() bool {
+	return r.FinalURL != "" && r.FinalURL != r.URL
+}
+
+// StatusError returns an error describing the response's status code
+func (r *Response) StatusError() *StatusError {
+	return &StatusError{StatusCode: r.StatusCode, RetryAfter: r.RetryAfter}
+}
+
+// ResponseFetcher is an abstraction that allows us to configure how
+// we fetch pages, and returns everything we know about a fetch
+type ResponseFetcher interface {
+	// FetchResponse fetches a URL. Responses with any status code are
+	// returned without an error, which is reserved for failed requests
+	FetchResponse(ctx context.Context, url string) (*Response, error)
+}
+
+// HeadFetcher is implemented by fetchers that can check a URL without downloading its body
+type HeadFetcher interface {
+	// Head makes a HEAD request to a URL. The response has no body
+	Head(ctx context.Context, url string) (*Response, error)
+}
+
+// fetcherAdapter turns a Fetcher into a ResponseFetcher
+type fetcherAdapter struct {
+	fetcher Fetcher
+}
+
+// Adapt returns a ResponseFetcher which fetches URLs with a Fetcher.
+// Fetchers that already implement ResponseFetcher are returned as they are.
+//
+// As a Fetcher only returns page bodies, responses are assumed to be
+// 200 OK responses from the requested URL, with a content type detected
+// from the body. A StatusError returned by the Fetcher is turned into a
+// response with its status code.
+func Adapt(f Fetcher) ResponseFetcher {
+	if responseFetcher, ok := f.(ResponseFetcher); ok {
+		return responseFetcher
+	}
+
+	return fetcherAdapter{fetcher: f}
+}
+
+// FetchResponse fetches a URL with the adapter's Fetcher
+func (a fetcherAdapter) FetchResponse(ctx context.Context, url string) (*Response, error) {
+	start := time.Now()
+
+	body, err := a.fetcher.Fetch(url)
+
+	response := &Response{
+		URL:      url,
+		FinalURL: url,
+		Header:   http.Header{},
+		Duration: time.Since(start),
+	}
+
+	var statusError *StatusError
+
+	if errors.As(err, &statusError) {
+		response.StatusCode = statusError.StatusCode
+		response.RetryAfter = statusError.RetryAfter
+
+		return response, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.StatusCode = http.StatusOK
+	response.ContentType = mediaType(http.DetectContentType(body))
+	response.Body = body
+
+	return response, nil
+}
+
+// mediaType returns the media type of a Content-Type header value without its parameters
+func mediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return contentType
+	}
+
+	return mediaType
+}
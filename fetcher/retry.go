@@ -0,0 +1,202 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryOn is a set of failures which can be retried
+type RetryOn int
+
+const (
+	// RetryTimeouts retries requests that timed out
+	RetryTimeouts RetryOn = 1 << iota
+
+	// RetryConnectionResets retries requests whose connection was reset or closed early
+	RetryConnectionResets
+
+	// RetryServerErrors retries 5xx responses
+	RetryServerErrors
+
+	// RetryTooManyRequests retries 429 responses
+	RetryTooManyRequests
+)
+
+// RetryAll retries every failure that can be retried
+const RetryAll = RetryTimeouts | RetryConnectionResets | RetryServerErrors | RetryTooManyRequests
+
+// retryOnNames maps the names used in ParseRetryOn to the failures they retry
+var retryOnNames = map[string]RetryOn{
+	"timeouts": RetryTimeouts,
+	"resets":   RetryConnectionResets,
+	"5xx":      RetryServerErrors,
+	"429":      RetryTooManyRequests,
+}
+
+// ParseRetryOn parses a comma separated list of failures to retry.
+// Valid failures are "timeouts", "resets", "5xx" and "429"
+func ParseRetryOn(value string) (RetryOn, error) {
+	var retryOn RetryOn
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		failure, exists := retryOnNames[name]
+
+		if !exists {
+			return 0, fmt.Errorf("unknown retry failure %q", name)
+		}
+
+		retryOn |= failure
+	}
+
+	return retryOn, nil
+}
+
+// IsTimeout checks if an error is caused by a request timing out
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netError net.Error
+
+	return errors.As(err, &netError) && netError.Timeout()
+}
+
+// IsConnectionReset checks if an error is caused by the server resetting
+// or closing the connection before sending a complete response.
+// A bare io.EOF only counts when the HTTP transport returned it, as it
+// means the connection was closed before a response was sent
+func IsConnectionReset(err error) bool {
+	var urlError *url.Error
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		(errors.As(err, &urlError) && errors.Is(urlError.Err, io.EOF))
+}
+
+// RetryPolicy decides which failed fetches are retried and how long
+// to wait before each retry
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a URL is fetched,
+	// including the first attempt. URLs are not retried if it is 1 or less
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. It doubles for every retry after that
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of each delay, from 0 to 1, that is randomized so
+	// retries from different workers are spread out
+	Jitter float64
+
+	// RetryOn is the set of failures that are retried
+	RetryOn RetryOn
+}
+
+// DefaultRetryPolicy returns a policy that fetches a URL up to 3 times,
+// retrying every failure that can be retried
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond * 500,
+		MaxDelay:    time.Second * 30,
+		Jitter:      0.5,
+		RetryOn:     RetryAll,
+	}
+}
+
+// Retryable checks if a failed fetch should be retried according to the policy.
+// A failed fetch either has an error or an unsuccessful response.
+func (p RetryPolicy) Retryable(response *Response, err error) bool {
+	if err != nil {
+		return (p.RetryOn&RetryTimeouts != 0 && IsTimeout(err)) ||
+			(p.RetryOn&RetryConnectionResets != 0 && IsConnectionReset(err))
+	}
+
+	if response == nil {
+		return false
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		return p.RetryOn&RetryTooManyRequests != 0
+	}
+
+	return p.RetryOn&RetryServerErrors != 0 && response.StatusCode >= 500
+}
+
+// Backoff returns how long to wait before retrying a URL that has failed a number of attempts
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+
+	for i := 1; i < attempts && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+
+	return delay
+}
+
+// Attempt is a record of a failed attempt to fetch a URL
+type Attempt struct {
+	// StatusCode is the HTTP status code of the response. It is 0 if there was no response
+	StatusCode int
+
+	// Err is the reason the attempt failed
+	Err error
+
+	// Duration is how long the attempt took
+	Duration time.Duration
+
+	// Delay is how long we waited before the next attempt. It is 0 for the last attempt
+	Delay time.Duration
+}
+
+// RetryError is returned when a URL could not be fetched.
+// It holds the history of every attempt made to fetch the URL
+type RetryError struct {
+	// URL is the URL that could not be fetched
+	URL string
+
+	// Attempts are the failed attempts, in order
+	Attempts []Attempt
+}
+
+func (e *RetryError) Error() string {
+	last := e.Attempts[len(e.Attempts)-1].Err
+
+	if len(e.Attempts) == 1 {
+		return last.Error()
+	}
+
+	return fmt.Sprintf("%v (after %v attempts)", last, len(e.Attempts))
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
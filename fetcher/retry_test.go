@@ -0,0 +1,134 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// timeoutError is a network error that timed out
+type timeoutError struct{}
+
+func (e timeoutError) Error() string   { return "i/o timeout" }
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		retryOn    RetryOn
+		statusCode int
+		err        error
+		want       bool
+	}{
+		{retryOn: RetryAll, err: timeoutError{}, want: true},
+		{retryOn: RetryAll, err: fmt.Errorf("request failed: %w", context.DeadlineExceeded), want: true},
+		{retryOn: RetryAll, err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{retryOn: RetryAll, err: io.ErrUnexpectedEOF, want: true},
+		{retryOn: RetryAll, err: &url.Error{Op: "Get", URL: "https://example.com", Err: io.EOF}, want: true},
+		{retryOn: RetryAll, err: fmt.Errorf("read body: %w", io.EOF), want: false},
+		{retryOn: RetryAll, err: errors.New("no such host"), want: false},
+		{retryOn: RetryAll, statusCode: http.StatusServiceUnavailable, want: true},
+		{retryOn: RetryAll, statusCode: http.StatusTooManyRequests, want: true},
+		{retryOn: RetryAll, statusCode: http.StatusNotFound, want: false},
+		{retryOn: RetryServerErrors, statusCode: http.StatusTooManyRequests, want: false},
+		{retryOn: RetryTooManyRequests, statusCode: http.StatusInternalServerError, want: false},
+		{retryOn: RetryServerErrors, err: timeoutError{}, want: false},
+		{retryOn: RetryConnectionResets, err: timeoutError{}, want: false},
+	}
+
+	for _, tc := range tests {
+		policy := RetryPolicy{MaxAttempts: 3, RetryOn: tc.retryOn}
+
+		var response *Response
+
+		if tc.err == nil {
+			response = &Response{StatusCode: tc.statusCode}
+		}
+
+		got := policy.Retryable(response, tc.err)
+
+		if got != tc.want {
+			t.Errorf("expected retryable to be %v for status %v and error %v, got %v", tc.want, tc.statusCode, tc.err, got)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Second * 5}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: time.Second * 2},
+		{attempts: 3, want: time.Second * 4},
+		{attempts: 4, want: time.Second * 5},
+		{attempts: 100, want: time.Second * 5},
+	}
+
+	for _, tc := range tests {
+		got := policy.Backoff(tc.attempts)
+
+		if got != tc.want {
+			t.Errorf("expected backoff after %v attempts to be %v, got %v", tc.attempts, tc.want, got)
+		}
+	}
+
+	// Jitter randomizes up to a fraction of the delay
+	policy.Jitter = 0.5
+
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(2)
+
+		if got < time.Second || got > time.Second*2 {
+			t.Fatalf("expected backoff with jitter to be between 1s and 2s, got %v", got)
+		}
+	}
+}
+
+func TestParseRetryOn(t *testing.T) {
+	retryOn, err := ParseRetryOn("timeouts, 5xx")
+
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	if retryOn != RetryTimeouts|RetryServerErrors {
+		t.Fatalf("expected timeouts and server errors to be retried, got %v", retryOn)
+	}
+
+	_, err = ParseRetryOn("timeouts,404")
+
+	if err == nil {
+		t.Fatalf("expected parse to fail for unknown failures")
+	}
+}
+
+func TestRetryError(t *testing.T) {
+	err := &RetryError{
+		URL: "https://example.com",
+		Attempts: []Attempt{
+			{Err: timeoutError{}, Delay: time.Second},
+			{StatusCode: http.StatusBadGateway, Err: &StatusError{StatusCode: http.StatusBadGateway}},
+		},
+	}
+
+	expected := "request failed with http 502 (after 2 attempts)"
+	if err.Error() != expected {
+		t.Fatalf("expected error message %v, got %v", expected, err.Error())
+	}
+
+	// The error of the last attempt can be unwrapped
+	var statusError *StatusError
+
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected retry error to unwrap to a 502 status error")
+	}
+}
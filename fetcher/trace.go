@@ -0,0 +1,133 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks down how long a request took into its phases. Phases that didn't
+// happen, such as connecting when a connection was reused, are 0. The phases of
+// every request made while following redirects are added together
+type Timing struct {
+	// DNS is how long it took to look up the host's address
+	DNS time.Duration
+
+	// Connect is how long it took to open a TCP connection to the host. When several
+	// of the host's addresses are dialed at once, only the dials that succeeded count
+	Connect time.Duration
+
+	// TLS is how long the TLS handshake took
+	TLS time.Duration
+
+	// FirstByte is how long it took the server to start responding
+	// after the request was written
+	FirstByte time.Duration
+
+	// Download is how long it took to read the response body after its first byte.
+	// The body of a redirect is read until the request for the next URL starts
+	Download time.Duration
+}
+
+// tracer times the phases of a request with a httptrace.ClientTrace.
+// Connections can be dialed in parallel, so its hooks can be called concurrently
+type tracer struct {
+	timing Timing
+
+	dnsStart     time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	// connectStarts holds when each address being dialed started to be dialed, keyed by address
+	connectStarts map[string]time.Time
+
+	mu sync.Mutex
+}
+
+// since adds the time since a start time to a phase
+func (t *tracer) since(phase *time.Duration, start *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !start.IsZero() {
+		*phase += time.Since(*start)
+	}
+}
+
+// mark records the current time in a time
+func (t *tracer) mark(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	*at = time.Now()
+}
+
+// connectStart records when an address started to be dialed
+func (t *tracer) connectStart(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.connectStarts == nil {
+		t.connectStarts = make(map[string]time.Time)
+	}
+
+	t.connectStarts[addr] = time.Now()
+}
+
+// connectDone adds the time it took to dial an address to the connect phase if the dial succeeded
+func (t *tracer) connectDone(addr string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, started := t.connectStarts[addr]
+	delete(t.connectStarts, addr)
+
+	if started && err == nil {
+		t.timing.Connect += time.Since(start)
+	}
+}
+
+// downloaded adds the time since the first byte of the last response to the download phase.
+// It is called when a redirect is followed and once the final body has been read
+func (t *tracer) downloaded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.firstByte.IsZero() {
+		t.timing.Download += time.Since(t.firstByte)
+		t.firstByte = time.Time{}
+	}
+}
+
+// trace returns the hooks that time a request's phases
+func (t *tracer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		// A request after the first is for a redirect, whose body has been read by now
+		GetConn:           func(string) { t.downloaded() },
+		DNSStart:          func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { t.since(&t.timing.DNS, &t.dnsStart) },
+		ConnectStart:      func(network, addr string) { t.connectStart(addr) },
+		ConnectDone:       func(network, addr string, err error) { t.connectDone(addr, err) },
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(&t.timing.TLS, &t.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() {
+			t.mark(&t.firstByte)
+			t.since(&t.timing.FirstByte, &t.wroteRequest)
+		},
+	}
+}
+
+// done returns the timing of the request once its body has been read
+func (t *tracer) done() Timing {
+	t.downloaded()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.timing
+}
@@ -4,5 +4,5 @@ go 1.15
 
 require (
 	github.com/PuerkitoBio/goquery v1.6.1
-	golang.org/x/net v0.0.0-20201224014010-6772e930b67b // indirect
+	golang.org/x/net v0.0.0-20201224014010-6772e930b67b
 )
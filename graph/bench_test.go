@@ -0,0 +1,204 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// siteLink is a link on a page of a generated site
+type siteLink struct {
+	from string
+	link page.Link
+}
+
+// generateSite returns the URLs and links of a site with a number of pages. Every page
+// links to the same navigation pages and loads the same assets like a real site's template,
+// and links to a few other pages with their own anchor text
+func generateSite(pages int) (urls []string, links []siteLink) {
+	pageURL := func(i int) string {
+		return fmt.Sprintf("https://www.example.com/articles/%v/a-reasonably-long-article-slug", i)
+	}
+
+	navigation := []string{}
+
+	for i := 0; i < 20; i++ {
+		navigation = append(navigation, fmt.Sprintf("https://www.example.com/section-%v", i))
+	}
+
+	assets := []string{"https://www.example.com/css/main.css", "https://www.example.com/js/app.js", "https://www.example.com/img/logo.png"}
+	urls = append(append(urls, navigation...), assets...)
+
+	for i := 0; i < pages; i++ {
+		urls = append(urls, pageURL(i))
+	}
+
+	for i := 0; i < pages; i++ {
+		from := pageURL(i)
+		position := 0
+
+		add := func(link page.Link) {
+			link.Position = position
+			links = append(links, siteLink{from: from, link: link})
+			position++
+		}
+
+		for _, asset := range assets {
+			add(page.Link{URL: asset, Element: "link", Attribute: "href", Kind: page.KindAsset})
+		}
+
+		for k, url := range navigation {
+			add(page.Link{URL: url, Text: fmt.Sprintf("Section %v", k), Element: "a", Attribute: "href", Kind: page.KindPage})
+		}
+
+		for k := 1; k <= 10; k++ {
+			target := (i*7 + k*13) % pages
+			add(page.Link{URL: pageURL(target), Text: fmt.Sprintf("Article %v", target), Element: "a", Attribute: "href", Kind: page.KindPage})
+		}
+
+		// The footer links to the first section again
+		add(page.Link{URL: navigation[0], Text: "Home", Element: "a", Attribute: "href", Kind: page.KindPage, Rel: []string{"nofollow"}})
+	}
+
+	return urls, links
+}
+
+// buildGraph adds the URLs and links of a generated site to a new graph
+func buildGraph(urls []string, links []siteLink) *Graph {
+	g := NewGraph()
+
+	for _, url := range urls {
+		g.AddNode(url)
+	}
+
+	for _, l := range links {
+		g.AddLink(l.from, l.link)
+	}
+
+	return g
+}
+
+// heapInUse returns the number of bytes allocated on the heap after a garbage collection
+func heapInUse() int64 {
+	var stats runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+
+	return int64(stats.HeapAlloc)
+}
+
+// BenchmarkGraphMemory reports how many bytes a graph uses for each of its nodes and edges
+func BenchmarkGraphMemory(b *testing.B) {
+	for _, pages := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("%v pages", pages), func(b *testing.B) {
+			urls, links := generateSite(pages)
+			graphs := make([]*Graph, 0, b.N)
+
+			before := heapInUse()
+
+			for i := 0; i < b.N; i++ {
+				graphs = append(graphs, buildGraph(urls, links))
+			}
+
+			b.StopTimer()
+
+			// The heap can shrink between the two readings, so the difference is signed
+			used := float64(heapInUse()-before) / float64(b.N)
+
+			// The site is kept alive until after the second reading, so freeing it
+			// isn't counted against the graphs
+			runtime.KeepAlive(urls)
+			runtime.KeepAlive(links)
+			runtime.KeepAlive(graphs)
+
+			b.ReportMetric(used/float64(graphs[0].NodeCount()), "bytes/node")
+			b.ReportMetric(used/float64(graphs[0].EdgeCount()), "bytes/edge")
+		})
+	}
+}
+
+// BenchmarkAddLinkParallel measures adding links to a graph from several goroutines at once,
+// like the crawler's coordinator and workers do
+func BenchmarkAddLinkParallel(b *testing.B) {
+	urls, links := generateSite(10000)
+	g := NewGraph()
+
+	for _, url := range urls {
+		g.AddNode(url)
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+
+		for pb.Next() {
+			l := links[i%len(links)]
+			g.AddLink(l.from, l.link)
+			i++
+		}
+	})
+}
+
+// BenchmarkReadsDuringWrites measures reading nodes while other goroutines set their metadata.
+// Readers and writers share the CPU, so the number of writes made for each read is reported too
+func BenchmarkReadsDuringWrites(b *testing.B) {
+	urls, links := generateSite(10000)
+	g := buildGraph(urls, links)
+
+	done := make(chan struct{})
+	writers := new(sync.WaitGroup)
+	var writes int64
+
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+
+		go func(w int) {
+			defer writers.Done()
+
+			for i := w; ; i += 4 {
+				select {
+				case <-done:
+					return
+				default:
+					g.SetStatus(urls[i%len(urls)], StatusFetched)
+					atomic.AddInt64(&writes, 1)
+				}
+			}
+		}(w)
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+
+		for pb.Next() {
+			g.Node(urls[i%len(urls)])
+			g.OutDegree(urls[i%len(urls)])
+			i++
+		}
+	})
+
+	b.StopTimer()
+	close(done)
+	writers.Wait()
+
+	b.ReportMetric(float64(atomic.LoadInt64(&writes))/float64(b.N), "writes/op")
+}
+
+// BenchmarkSnapshot measures copying a graph, which every report does
+func BenchmarkSnapshot(b *testing.B) {
+	g := buildGraph(generateSite(10000))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g.Snapshot()
+	}
+}
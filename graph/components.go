@@ -0,0 +1,268 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// tarjan finds the strongly connected components of the index with Tarjan's algorithm.
+// Each component is a list of page positions
+func (index linkGraph) tarjan() [][]int {
+	n := len(index.urls)
+
+	// order is the order in which each page was first visited, starting from 1
+	order := make([]int, n)
+
+	// lowest is the lowest order of the pages reachable from each page
+	// through the pages on the stack
+	lowest := make([]int, n)
+
+	onStack := make([]bool, n)
+	stack := []int{}
+	visited := 0
+	components := [][]int{}
+
+	var connect func(current int)
+
+	connect = func(current int) {
+		visited++
+		order[current] = visited
+		lowest[current] = visited
+		stack = append(stack, current)
+		onStack[current] = true
+
+		for _, next := range index.outlinks[current] {
+			if order[next] == 0 {
+				connect(next)
+
+				if lowest[next] < lowest[current] {
+					lowest[current] = lowest[next]
+				}
+			} else if onStack[next] && order[next] < lowest[current] {
+				lowest[current] = order[next]
+			}
+		}
+
+		// The page is the root of a component made up of every page above it on the stack
+		if lowest[current] != order[current] {
+			return
+		}
+
+		component := []int{}
+
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+
+			if top == current {
+				break
+			}
+		}
+
+		components = append(components, component)
+	}
+
+	for i := 0; i < n; i++ {
+		if order[i] == 0 {
+			connect(i)
+		}
+	}
+
+	return components
+}
+
+// components returns the strongly connected components of the index as sorted lists of URLs.
+// Components with a single page are only returned if the page links to itself and
+// all is false. Components are sorted from the largest to the smallest
+func (index linkGraph) components(all bool) [][]string {
+	return index.sortComponents(index.tarjan(), all)
+}
+
+// sortComponents turns strongly connected components found by tarjan into sorted lists of
+// URLs, sorted from the largest to the smallest. Components with a single page are only
+// returned if the page links to itself and all is false
+func (index linkGraph) sortComponents(positions [][]int, all bool) [][]string {
+	components := [][]string{}
+
+	for _, component := range positions {
+		if !all && len(component) == 1 && !index.linksToItself(component[0]) {
+			continue
+		}
+
+		urls := []string{}
+
+		for _, position := range component {
+			urls = append(urls, index.urls[position])
+		}
+
+		sort.Strings(urls)
+		components = append(components, urls)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		if len(components[i]) != len(components[j]) {
+			return len(components[i]) > len(components[j])
+		}
+
+		return components[i][0] < components[j][0]
+	})
+
+	return components
+}
+
+// linksToItself checks if the page at a position links to itself
+func (index linkGraph) linksToItself(position int) bool {
+	for _, next := range index.outlinks[position] {
+		if next == position {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StronglyConnectedComponents returns the groups of pages in the graph that can
+// all be reached from each other by following links and redirects.
+// URLs in each component are sorted, and components are sorted from the largest to the smallest
+func (g *Graph) StronglyConnectedComponents() [][]string {
+	return g.linkGraph(clickable).components(true)
+}
+
+// Cycles returns the groups of pages that link to each other in a cycle, i.e.
+// every strongly connected component with more than one page, and pages that link to themselves
+func (g *Graph) Cycles() [][]string {
+	return g.linkGraph(clickable).components(false)
+}
+
+// RedirectLoops returns the groups of URLs that redirect to each other in a loop
+func (g *Graph) RedirectLoops() [][]string {
+	return g.linkGraph(func(edge Edge) bool {
+		return edge.Kind == KindRedirect
+	}).components(false)
+}
+
+// DeadEnds returns the URLs of fetched pages that don't link to any other page in the graph
+func (g *Graph) DeadEnds() []string {
+	return g.deadEnds(g.linkGraph(clickable))
+}
+
+// deadEnds returns the URLs of fetched pages that don't link to any other page in an index of the graph
+func (g *Graph) deadEnds(index linkGraph) []string {
+	deadEnds := []string{}
+
+	for i, url := range index.urls {
+		if status, _ := g.Status(url); status != StatusFetched {
+			continue
+		}
+
+		if len(index.outlinks[i]) == 0 || (len(index.outlinks[i]) == 1 && index.linksToItself(i)) {
+			deadEnds = append(deadEnds, url)
+		}
+	}
+
+	return deadEnds
+}
+
+// Orphans returns the URLs from a list of pages known to exist, such as a sitemap,
+// that can't be reached from a seed URL by following links. The URLs are normalized and sorted
+func (g *Graph) Orphans(seed string, urls []string) []string {
+	return g.orphans(g.linkGraph(clickable), seed, urls)
+}
+
+// orphans returns the URLs from a list of pages known to exist that can't
+// be reached from a seed URL in an index of the graph
+func (g *Graph) orphans(index linkGraph, seed string, urls []string) []string {
+	clickDepths := index.clickDepths(g.key(seed))
+	seen := make(map[string]bool)
+	orphans := []string{}
+
+	for _, url := range urls {
+		url = g.key(url)
+
+		if _, reachable := clickDepths[url]; reachable || seen[url] {
+			continue
+		}
+
+		seen[url] = true
+		orphans = append(orphans, url)
+	}
+
+	sort.Strings(orphans)
+
+	return orphans
+}
+
+// StructureReport describes problems with how a site's pages are linked together
+type StructureReport struct {
+	// Components is the number of strongly connected components of the graph
+	Components int
+
+	// Cycles are the groups of pages that link to each other in a cycle
+	Cycles [][]string
+
+	// RedirectLoops are the groups of URLs that redirect to each other in a loop
+	RedirectLoops [][]string
+
+	// DeadEnds are the fetched pages that don't link to any other page
+	DeadEnds []string
+
+	// Orphans are the pages known to exist that can't be reached from the seed URL
+	Orphans []string
+}
+
+// StructureReport returns a report of the cycles, redirect loops and dead ends in
+// the graph, along with orphans from a list of pages known to exist
+func (g *Graph) StructureReport(seed string, urls []string) StructureReport {
+	snapshot := g.Snapshot()
+
+	// The links between pages are indexed and their components found once for the whole report
+	index := snapshot.linkGraph(clickable)
+	components := index.tarjan()
+
+	return StructureReport{
+		Components:    len(components),
+		Cycles:        index.sortComponents(components, false),
+		RedirectLoops: snapshot.RedirectLoops(),
+		DeadEnds:      snapshot.deadEnds(index),
+		Orphans:       snapshot.orphans(index, seed, urls),
+	}
+}
+
+// Write writes the report to a writer
+func (r StructureReport) Write(w io.Writer) error {
+	message := fmt.Sprintf("🧩 %v strongly connected components, %v link cycles\n", r.Components, len(r.Cycles))
+
+	for _, cycle := range r.Cycles {
+		message += fmt.Sprintf("\t 🔁 %v pages link to each other, including %v\n", len(cycle), cycle[0])
+	}
+
+	if len(r.RedirectLoops) > 0 {
+		message += fmt.Sprintf("♾ %v redirect loops:\n", len(r.RedirectLoops))
+
+		for _, loop := range r.RedirectLoops {
+			message += fmt.Sprintf("\t %v\n", loop)
+		}
+	}
+
+	if len(r.DeadEnds) > 0 {
+		message += fmt.Sprintf("🧱 %v pages don't link to any other page:\n", len(r.DeadEnds))
+
+		for _, url := range r.DeadEnds {
+			message += fmt.Sprintf("\t %v\n", url)
+		}
+	}
+
+	if len(r.Orphans) > 0 {
+		message += fmt.Sprintf("👻 %v orphan pages can't be reached by following links:\n", len(r.Orphans))
+
+		for _, url := range r.Orphans {
+			message += fmt.Sprintf("\t %v\n", url)
+		}
+	}
+
+	_, err := io.WriteString(w, message)
+	return err
+}
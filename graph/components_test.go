@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"strings"
+	"testing"
+)
+
+// newComponentGraph returns a graph where the home and about pages link
+// to each other, the about page links to a contact page that doesn't link
+// anywhere, an old page redirects to a new page which redirects back to
+// it, and a page links to itself
+func newComponentGraph(t *testing.T) *Graph {
+	g := NewGraph()
+
+	urls := []string{
+		"https://example.com",
+		"https://example.com/about",
+		"https://example.com/contact",
+		"https://example.com/old",
+		"https://example.com/new",
+		"https://example.com/mirror",
+	}
+
+	for _, url := range urls {
+		g.AddNode(url)
+		g.SetStatus(url, StatusFetched)
+	}
+
+	links := []struct {
+		from string
+		link page.Link
+	}{
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/about"}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/old", Position: 1}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com"}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com/contact", Position: 1}},
+		{from: "https://example.com/old", link: page.Link{URL: "https://example.com/new", Kind: page.KindRedirect}},
+		{from: "https://example.com/new", link: page.Link{URL: "https://example.com/old", Kind: page.KindRedirect}},
+		{from: "https://example.com/mirror", link: page.Link{URL: "https://example.com/mirror"}},
+	}
+
+	for _, l := range links {
+		if err := g.AddLink(l.from, l.link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	components := newComponentGraph(t).StronglyConnectedComponents()
+
+	expected := "[[https://example.com https://example.com/about] [https://example.com/new https://example.com/old] [https://example.com/contact] [https://example.com/mirror]]"
+
+	if fmt.Sprint(components) != expected {
+		t.Fatalf("expected components %v, got %v", expected, components)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := newComponentGraph(t)
+
+	expected := "[[https://example.com https://example.com/about] [https://example.com/new https://example.com/old] [https://example.com/mirror]]"
+
+	if cycles := g.Cycles(); fmt.Sprint(cycles) != expected {
+		t.Fatalf("expected cycles %v, got %v", expected, cycles)
+	}
+
+	expected = "[[https://example.com/new https://example.com/old]]"
+
+	if loops := g.RedirectLoops(); fmt.Sprint(loops) != expected {
+		t.Fatalf("expected redirect loops %v, got %v", expected, loops)
+	}
+}
+
+func TestDeadEnds(t *testing.T) {
+	g := newComponentGraph(t)
+
+	// Pages that weren't fetched aren't dead ends, since their links are unknown
+	g.AddNode("https://example.com/admin")
+	g.AddEdge("https://example.com", "https://example.com/admin")
+
+	deadEnds := g.DeadEnds()
+
+	if strings.Join(deadEnds, " ") != "https://example.com/contact https://example.com/mirror" {
+		t.Fatalf("expected the contact and mirror pages to be dead ends, got %v", deadEnds)
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	g := newComponentGraph(t)
+
+	sitemap := []string{
+		"https://example.com/about/",
+		"https://example.com/mirror",
+		"https://example.com/pricing",
+		"https://EXAMPLE.com/pricing#plans",
+	}
+
+	orphans := g.Orphans("https://example.com", sitemap)
+
+	if strings.Join(orphans, " ") != "https://example.com/mirror https://example.com/pricing" {
+		t.Fatalf("expected the mirror and pricing pages to be orphans, got %v", orphans)
+	}
+
+	report := g.StructureReport("https://example.com", sitemap)
+
+	if report.Components != 4 || len(report.Cycles) != 3 || len(report.RedirectLoops) != 1 || len(report.DeadEnds) != 2 || len(report.Orphans) != 2 {
+		t.Fatalf("unexpected structure report: %+v", report)
+	}
+
+	var b bytes.Buffer
+
+	if err := report.Write(&b); err != nil {
+		t.Fatalf("write report error: %v", err)
+	}
+
+	if !strings.Contains(b.String(), "2 orphan pages") {
+		t.Fatalf("expected report to list orphan pages, got %v", b.String())
+	}
+}
@@ -0,0 +1,227 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// StatusCodeChange is a URL whose HTTP status code changed between two crawls
+type StatusCodeChange struct {
+	URL string
+	Old int
+	New int
+}
+
+// TitleChange is a page whose title changed between two crawls
+type TitleChange struct {
+	URL string
+	Old string
+	New string
+}
+
+// OutlinkChange describes the links added to and removed from a page between two crawls
+type OutlinkChange struct {
+	// URL is the URL of the page
+	URL string
+
+	// Added are the URLs the page links to that it didn't link to before, sorted
+	Added []string
+
+	// Removed are the URLs the page no longer links to, sorted
+	Removed []string
+}
+
+// Diff describes how a site's graph changed between two crawls
+type Diff struct {
+	// Added are the URLs only found by the newer crawl, sorted
+	Added []string
+
+	// Removed are the URLs only found by the older crawl, sorted
+	Removed []string
+
+	// StatusCodes are the URLs found by both crawls whose HTTP status code changed
+	StatusCodes []StatusCodeChange
+
+	// Titles are the pages fetched by both crawls whose title changed
+	Titles []TitleChange
+
+	// BrokenLinks are the links in the newer crawl to broken URLs that
+	// weren't broken links in the older crawl
+	BrokenLinks []Edge
+
+	// Outlinks are the pages fetched by both crawls whose hyperlinks changed
+	Outlinks []OutlinkChange
+}
+
+// broken checks if a node's URL couldn't be fetched, or was served with an error status code
+func (n Node) broken() bool {
+	return n.status == StatusBroken || n.status == StatusFailed || n.statusCode >= 400
+}
+
+// Diff compares the graph of an older crawl with the graph of a newer crawl of the same site
+func (g *Graph) Diff(newer *Graph) Diff {
+	oldSnapshot, newSnapshot := g.Snapshot(), newer.Snapshot()
+
+	diff := Diff{
+		Added:       []string{},
+		Removed:     []string{},
+		StatusCodes: []StatusCodeChange{},
+		Titles:      []TitleChange{},
+		BrokenLinks: []Edge{},
+		Outlinks:    []OutlinkChange{},
+	}
+
+	for _, node := range oldSnapshot.Nodes() {
+		if !newSnapshot.HasNode(node.url) {
+			diff.Removed = append(diff.Removed, node.url)
+		}
+	}
+
+	for _, node := range newSnapshot.Nodes() {
+		old, exists := oldSnapshot.Node(node.url)
+
+		if !exists {
+			diff.Added = append(diff.Added, node.url)
+			continue
+		}
+
+		if old.statusCode != node.statusCode {
+			diff.StatusCodes = append(diff.StatusCodes, StatusCodeChange{URL: node.url, Old: old.statusCode, New: node.statusCode})
+		}
+
+		if old.status != StatusFetched || node.status != StatusFetched {
+			continue
+		}
+
+		if old.title != node.title {
+			diff.Titles = append(diff.Titles, TitleChange{URL: node.url, Old: old.title, New: node.title})
+		}
+
+		if change := outlinkChange(oldSnapshot, newSnapshot, node.url); len(change.Added) > 0 || len(change.Removed) > 0 {
+			diff.Outlinks = append(diff.Outlinks, change)
+		}
+	}
+
+	for _, edge := range newSnapshot.Edges() {
+		if target, _ := newSnapshot.Node(edge.To); !target.broken() {
+			continue
+		}
+
+		if target, _ := oldSnapshot.Node(edge.To); hasEdge(oldSnapshot, edge) && target.broken() {
+			continue
+		}
+
+		diff.BrokenLinks = append(diff.BrokenLinks, edge)
+	}
+
+	return diff
+}
+
+// outlinkChange compares the URLs a page links to with hyperlinks in two graphs
+func outlinkChange(older, newer *Graph, url string) OutlinkChange {
+	oldTargets, newTargets := hyperlinkTargets(older, url), hyperlinkTargets(newer, url)
+	change := OutlinkChange{URL: url, Added: []string{}, Removed: []string{}}
+
+	for target := range newTargets {
+		if !oldTargets[target] {
+			change.Added = append(change.Added, target)
+		}
+	}
+
+	for target := range oldTargets {
+		if !newTargets[target] {
+			change.Removed = append(change.Removed, target)
+		}
+	}
+
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+
+	return change
+}
+
+// hyperlinkTargets returns the set of URLs a page links to with hyperlinks
+func hyperlinkTargets(g *Graph, url string) map[string]bool {
+	targets := make(map[string]bool)
+
+	for _, edge := range g.Outlinks(url) {
+		if edge.Kind == KindHyperlink {
+			targets[edge.To] = true
+		}
+	}
+
+	return targets
+}
+
+// hasEdge checks if a graph has an edge of the same kind between the same URLs as an edge
+func hasEdge(g *Graph, edge Edge) bool {
+	for _, existing := range g.Outlinks(edge.From) {
+		if existing.To == edge.To && existing.Kind == edge.Kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Regressed checks if the newer crawl lost URLs or has new broken links
+func (d Diff) Regressed() bool {
+	return len(d.Removed) > 0 || len(d.BrokenLinks) > 0
+}
+
+// Write writes the diff to a writer
+func (d Diff) Write(w io.Writer) error {
+	message := fmt.Sprintf("🆕 %v URLs added, 🗑 %v URLs removed\n", len(d.Added), len(d.Removed))
+
+	for _, url := range d.Added {
+		message += fmt.Sprintf("\t + %v\n", url)
+	}
+
+	for _, url := range d.Removed {
+		message += fmt.Sprintf("\t - %v\n", url)
+	}
+
+	if len(d.StatusCodes) > 0 {
+		message += fmt.Sprintf("🚦 %v status codes changed:\n", len(d.StatusCodes))
+
+		for _, change := range d.StatusCodes {
+			message += fmt.Sprintf("\t %v: %v → %v\n", change.URL, change.Old, change.New)
+		}
+	}
+
+	if len(d.BrokenLinks) > 0 {
+		message += fmt.Sprintf("💔 %v new broken links:\n", len(d.BrokenLinks))
+
+		for _, edge := range d.BrokenLinks {
+			message += fmt.Sprintf("\t %v → %v (%v)\n", edge.From, edge.To, edge.Kind)
+		}
+	}
+
+	if len(d.Outlinks) > 0 {
+		message += fmt.Sprintf("🔀 %v pages changed their links:\n", len(d.Outlinks))
+
+		for _, change := range d.Outlinks {
+			message += fmt.Sprintf("\t %v\n", change.URL)
+
+			for _, url := range change.Added {
+				message += fmt.Sprintf("\t\t + %v\n", url)
+			}
+
+			for _, url := range change.Removed {
+				message += fmt.Sprintf("\t\t - %v\n", url)
+			}
+		}
+	}
+
+	if len(d.Titles) > 0 {
+		message += fmt.Sprintf("🏷 %v titles changed:\n", len(d.Titles))
+
+		for _, change := range d.Titles {
+			message += fmt.Sprintf("\t %v: %q → %q\n", change.URL, change.Old, change.New)
+		}
+	}
+
+	_, err := io.WriteString(w, message)
+	return err
+}
@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"bytes"
+	"github.com/darthchudi/crwl/page"
+	"strings"
+	"testing"
+)
+
+// newCrawlGraph returns the graph of a crawl of a site. Fetched pages are given a title and a
+// status code, and a list of links is added between them
+func newCrawlGraph(t *testing.T, titles map[string]string, statusCodes map[string]int, links [][2]string) *Graph {
+	g := NewGraph()
+
+	for url, statusCode := range statusCodes {
+		g.AddNode(url)
+		g.SetFetch(url, Fetch{StatusCode: statusCode})
+
+		if statusCode >= 400 {
+			g.SetStatus(url, StatusFailed)
+			continue
+		}
+
+		g.SetStatus(url, StatusFetched)
+		g.SetTitle(url, titles[url])
+	}
+
+	for i, link := range links {
+		if err := g.AddLink(link[0], page.Link{URL: link[1], Position: i}); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+func TestDiff(t *testing.T) {
+	old := newCrawlGraph(t,
+		map[string]string{"https://example.com": "Home", "https://example.com/about": "About", "https://example.com/careers": "Careers"},
+		map[string]int{"https://example.com": 200, "https://example.com/about": 200, "https://example.com/careers": 200, "https://example.com/old": 404},
+		[][2]string{
+			{"https://example.com", "https://example.com/about"},
+			{"https://example.com", "https://example.com/careers"},
+			{"https://example.com/about", "https://example.com/old"},
+		},
+	)
+
+	newer := newCrawlGraph(t,
+		map[string]string{"https://example.com": "Home", "https://example.com/about": "About us", "https://example.com/blog": "Blog"},
+		map[string]int{"https://example.com": 200, "https://example.com/about": 500, "https://example.com/blog": 200, "https://example.com/old": 404},
+		[][2]string{
+			{"https://example.com", "https://example.com/about"},
+			{"https://example.com", "https://example.com/blog"},
+			{"https://example.com/about", "https://example.com/old"},
+			{"https://example.com/blog", "https://example.com/old"},
+		},
+	)
+
+	diff := old.Diff(newer)
+
+	if strings.Join(diff.Added, " ") != "https://example.com/blog" || strings.Join(diff.Removed, " ") != "https://example.com/careers" {
+		t.Errorf("expected the blog to be added and careers to be removed, got %v and %v", diff.Added, diff.Removed)
+	}
+
+	if len(diff.StatusCodes) != 1 || diff.StatusCodes[0] != (StatusCodeChange{URL: "https://example.com/about", Old: 200, New: 500}) {
+		t.Errorf("expected the about page status code to change, got %+v", diff.StatusCodes)
+	}
+
+	// The about page wasn't fetched by the newer crawl, so its title and links aren't compared
+	if len(diff.Titles) != 0 {
+		t.Errorf("expected no title changes, got %+v", diff.Titles)
+	}
+
+	if len(diff.Outlinks) != 1 || diff.Outlinks[0].URL != "https://example.com" ||
+		strings.Join(diff.Outlinks[0].Added, " ") != "https://example.com/blog" || strings.Join(diff.Outlinks[0].Removed, " ") != "https://example.com/careers" {
+		t.Errorf("expected the home page to link to the blog instead of careers, got %+v", diff.Outlinks)
+	}
+
+	// The link from the about page to the old page was already broken
+	broken := []string{}
+
+	for _, edge := range diff.BrokenLinks {
+		broken = append(broken, edge.From+" -> "+edge.To)
+	}
+
+	expected := "https://example.com -> https://example.com/about, https://example.com/blog -> https://example.com/old"
+
+	if strings.Join(broken, ", ") != expected {
+		t.Errorf("expected new broken links %v, got %v", expected, broken)
+	}
+
+	if !diff.Regressed() {
+		t.Errorf("expected the diff to be a regression")
+	}
+
+	var b bytes.Buffer
+	diff.Write(&b)
+
+	if !strings.Contains(b.String(), "💔 2 new broken links") || !strings.Contains(b.String(), "\t https://example.com/about: 200 → 500\n") {
+		t.Errorf("unexpected diff report:\n%v", b.String())
+	}
+}
+
+func TestDiffTitles(t *testing.T) {
+	statusCodes := map[string]int{"https://example.com": 200}
+
+	old := newCrawlGraph(t, map[string]string{"https://example.com": "Home"}, statusCodes, nil)
+	newer := newCrawlGraph(t, map[string]string{"https://example.com": "Welcome"}, statusCodes, nil)
+
+	diff := old.Diff(newer)
+
+	if len(diff.Titles) != 1 || diff.Titles[0] != (TitleChange{URL: "https://example.com", Old: "Home", New: "Welcome"}) {
+		t.Errorf("expected the home page title to change, got %+v", diff.Titles)
+	}
+
+	if diff.Regressed() || len(old.Diff(old).Titles) != 0 {
+		t.Errorf("expected title changes not to be a regression")
+	}
+}
@@ -0,0 +1,326 @@
+package graph
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Formats the graph can be exported to
+const (
+	// FormatDOT is the Graphviz DOT language
+	FormatDOT = "dot"
+
+	// FormatGraphML is the GraphML XML format
+	FormatGraphML = "graphml"
+
+	// FormatGEXF is the GEXF XML format used by Gephi
+	FormatGEXF = "gexf"
+)
+
+// ErrUnknownFormat is returned when exporting the graph to a format that isn't supported
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Export writes the graph to a writer in a format: FormatDOT, FormatGraphML or FormatGEXF
+func (g *Graph) Export(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case FormatDOT:
+		return g.WriteDOT(w)
+	case FormatGraphML:
+		return g.WriteGraphML(w)
+	case FormatGEXF:
+		return g.WriteGEXF(w)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// dotQuote quotes a string as a DOT identifier
+func dotQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// WriteDOT writes the graph to a writer in the Graphviz DOT language.
+// Nodes are labelled with their page title, or their URL if they have no title
+func (g *Graph) WriteDOT(w io.Writer) error {
+	snapshot := g.Snapshot()
+	ranks, hubs, authorities := exportScores(snapshot)
+
+	var b strings.Builder
+
+	b.WriteString("digraph crawl {\n")
+
+	for _, node := range snapshot.Nodes() {
+		label := node.Title()
+
+		if label == "" {
+			label = node.URL()
+		}
+
+		fmt.Fprintf(&b, "\t%v [label=%v, url=%v, status=%v, depth=%v, pagerank=%v, hub=%v, authority=%v];\n",
+			dotQuote(node.URL()), dotQuote(label), dotQuote(node.URL()), dotQuote(string(node.Status())), node.Depth(),
+			formatScore(ranks[node.URL()]), formatScore(hubs[node.URL()]), formatScore(authorities[node.URL()]))
+	}
+
+	for _, edge := range snapshot.Edges() {
+		fmt.Fprintf(&b, "\t%v -> %v [label=%v, kind=%v, weight=%v, rel=%v];\n",
+			dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Link.Text), dotQuote(string(edge.Kind)), edge.Weight,
+			dotQuote(strings.Join(edge.Link.Rel, " ")))
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatScore formats a score as a node attribute value
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 6, 64)
+}
+
+// exportScores returns the PageRank, hub and authority scores nodes are exported with, keyed by URL
+func exportScores(g *Graph) (ranks, hubs, authorities map[string]float64) {
+	opts := DefaultRankOptions()
+	hubs, authorities = g.HITS(opts)
+
+	return g.PageRank(opts), hubs, authorities
+}
+
+// exportIDs returns the IDs nodes are written with in XML formats, keyed by URL
+func exportIDs(nodes []Node) map[string]string {
+	ids := make(map[string]string, len(nodes))
+
+	for i, node := range nodes {
+		ids[node.URL()] = "n" + strconv.Itoa(i)
+	}
+
+	return ids
+}
+
+// writeXML writes a document as indented XML with an XML header
+func writeXML(w io.Writer, document interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph to a writer in the GraphML format
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	snapshot := g.Snapshot()
+	nodes := snapshot.Nodes()
+	ids := exportIDs(nodes)
+	ranks, hubs, authorities := exportScores(snapshot)
+
+	document := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "url", For: "node", Name: "url", Type: "string"},
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "status", For: "node", Name: "status", Type: "string"},
+			{ID: "depth", For: "node", Name: "depth", Type: "int"},
+			{ID: "pagerank", For: "node", Name: "pagerank", Type: "double"},
+			{ID: "hub", For: "node", Name: "hub", Type: "double"},
+			{ID: "authority", For: "node", Name: "authority", Type: "double"},
+			{ID: "text", For: "edge", Name: "text", Type: "string"},
+			{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+			{ID: "rel", For: "edge", Name: "rel", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "crawl", EdgeDefault: "directed"},
+	}
+
+	for _, node := range nodes {
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{
+			ID: ids[node.URL()],
+			Data: []graphMLData{
+				{Key: "url", Value: node.URL()},
+				{Key: "title", Value: node.Title()},
+				{Key: "status", Value: string(node.Status())},
+				{Key: "depth", Value: strconv.Itoa(node.Depth())},
+				{Key: "pagerank", Value: formatScore(ranks[node.URL()])},
+				{Key: "hub", Value: formatScore(hubs[node.URL()])},
+				{Key: "authority", Value: formatScore(authorities[node.URL()])},
+			},
+		})
+	}
+
+	for i, edge := range snapshot.Edges() {
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: ids[edge.From],
+			Target: ids[edge.To],
+			Data: []graphMLData{
+				{Key: "text", Value: edge.Link.Text},
+				{Key: "kind", Value: string(edge.Kind)},
+				{Key: "weight", Value: strconv.Itoa(edge.Weight)},
+				{Key: "rel", Value: strings.Join(edge.Link.Rel, " ")},
+			},
+		})
+	}
+
+	return writeXML(w, document)
+}
+
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	XMLNS   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	Weight    int            `xml:"weight,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// WriteGEXF writes the graph to a writer in the GEXF format used by Gephi.
+// Nodes are labelled with their URL, and edges with their anchor text and weighted by
+// the number of times their link was found
+func (g *Graph) WriteGEXF(w io.Writer) error {
+	snapshot := g.Snapshot()
+	nodes := snapshot.Nodes()
+	ids := exportIDs(nodes)
+	ranks, hubs, authorities := exportScores(snapshot)
+
+	document := gexfDocument{
+		XMLNS:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: []gexfAttribute{
+					{ID: "title", Title: "title", Type: "string"},
+					{ID: "status", Title: "status", Type: "string"},
+					{ID: "depth", Title: "depth", Type: "integer"},
+					{ID: "pagerank", Title: "pagerank", Type: "double"},
+					{ID: "hub", Title: "hub", Type: "double"},
+					{ID: "authority", Title: "authority", Type: "double"},
+				}},
+				{Class: "edge", Attributes: []gexfAttribute{
+					{ID: "text", Title: "text", Type: "string"},
+					{ID: "kind", Title: "kind", Type: "string"},
+					{ID: "rel", Title: "rel", Type: "string"},
+				}},
+			},
+		},
+	}
+
+	for _, node := range nodes {
+		document.Graph.Nodes = append(document.Graph.Nodes, gexfNode{
+			ID:    ids[node.URL()],
+			Label: node.URL(),
+			AttValues: []gexfAttValue{
+				{For: "title", Value: node.Title()},
+				{For: "status", Value: string(node.Status())},
+				{For: "depth", Value: strconv.Itoa(node.Depth())},
+				{For: "pagerank", Value: formatScore(ranks[node.URL()])},
+				{For: "hub", Value: formatScore(hubs[node.URL()])},
+				{For: "authority", Value: formatScore(authorities[node.URL()])},
+			},
+		})
+	}
+
+	for i, edge := range snapshot.Edges() {
+		document.Graph.Edges = append(document.Graph.Edges, gexfEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: ids[edge.From],
+			Target: ids[edge.To],
+			Label:  edge.Link.Text,
+			Weight: edge.Weight,
+			AttValues: []gexfAttValue{
+				{For: "text", Value: edge.Link.Text},
+				{For: "kind", Value: string(edge.Kind)},
+				{For: "rel", Value: strings.Join(edge.Link.Rel, " ")},
+			},
+		})
+	}
+
+	return writeXML(w, document)
+}
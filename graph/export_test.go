@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"github.com/darthchudi/crwl/page"
+	"testing"
+)
+
+// newExportGraph returns a graph with a home page linking to a disallowed about page
+func newExportGraph(t *testing.T) *Graph {
+	g := NewGraph()
+
+	g.AddNode("https://example.com")
+	g.AddNode("https://example.com/about")
+	g.SetTitle("https://example.com", `Home "Sweet" Home`)
+	g.SetDepth("https://example.com/about", 1)
+	g.SetStatus("https://example.com/about", StatusDisallowed)
+
+	link := page.Link{URL: "https://example.com/about", Text: "About & us", Rel: []string{"nofollow"}}
+
+	// The link is found twice, so its edge has a weight of 2
+	for i := 0; i < 2; i++ {
+		if err := g.AddLink("https://example.com", link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := newExportGraph(t)
+
+	var b bytes.Buffer
+
+	if err := g.Export(&b, FormatDOT); err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+
+	expected := `digraph crawl {
+	"https://example.com" [label="Home \"Sweet\" Home", url="https://example.com", status="", depth=0, pagerank=0.500000, hub=0.000000, authority=0.000000];
+	"https://example.com/about" [label="https://example.com/about", url="https://example.com/about", status="disallowed", depth=1, pagerank=0.500000, hub=0.000000, authority=0.000000];
+	"https://example.com" -> "https://example.com/about" [label="About & us", kind="hyperlink", weight=2, rel="nofollow"];
+}
+`
+
+	if b.String() != expected {
+		t.Fatalf("expected dot output:\n%v\ngot:\n%v", expected, b.String())
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	g := newExportGraph(t)
+
+	var b bytes.Buffer
+
+	if err := g.Export(&b, FormatGraphML); err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+
+	var document graphMLDocument
+
+	if err := xml.Unmarshal(b.Bytes(), &document); err != nil {
+		t.Fatalf("invalid graphml: %v", err)
+	}
+
+	nodes, edges := document.Graph.Nodes, document.Graph.Edges
+
+	if len(nodes) != 2 || len(edges) != 1 {
+		t.Fatalf("expected 2 nodes and 1 edge, got %v and %v", len(nodes), len(edges))
+	}
+
+	if nodes[1].Data[2].Value != "disallowed" || nodes[1].Data[3].Value != "1" {
+		t.Fatalf("unexpected about node data: %+v", nodes[1].Data)
+	}
+
+	if edges[0].Source != nodes[0].ID || edges[0].Target != nodes[1].ID || edges[0].Data[0].Value != "About & us" || edges[0].Data[2].Value != "2" {
+		t.Fatalf("unexpected edge: %+v", edges[0])
+	}
+}
+
+func TestWriteGEXF(t *testing.T) {
+	g := newExportGraph(t)
+
+	var b bytes.Buffer
+
+	if err := g.Export(&b, "GEXF"); err != nil {
+		t.Fatalf("export error: %v", err)
+	}
+
+	var document gexfDocument
+
+	if err := xml.Unmarshal(b.Bytes(), &document); err != nil {
+		t.Fatalf("invalid gexf: %v", err)
+	}
+
+	nodes, edges := document.Graph.Nodes, document.Graph.Edges
+
+	if len(nodes) != 2 || len(edges) != 1 {
+		t.Fatalf("expected 2 nodes and 1 edge, got %v and %v", len(nodes), len(edges))
+	}
+
+	if nodes[0].Label != "https://example.com" || nodes[0].AttValues[0].Value != `Home "Sweet" Home` {
+		t.Fatalf("unexpected home node: %+v", nodes[0])
+	}
+
+	if edges[0].Label != "About & us" || edges[0].Weight != 2 || edges[0].AttValues[1].Value != "hyperlink" || edges[0].AttValues[2].Value != "nofollow" {
+		t.Fatalf("unexpected edge: %+v", edges[0])
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	err := NewGraph().Export(&bytes.Buffer{}, "svg")
+
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected an unknown format error, got %v", err)
+	}
+}
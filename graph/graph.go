@@ -4,75 +4,614 @@ package graph
 
 import (
 	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"github.com/darthchudi/crwl/urlnorm"
+	"strings"
 	"sync"
+	"time"
+)
+
+// Status describes what the crawler did with a node's URL
+type Status string
+
+const (
+	// StatusDisallowed means the URL was not fetched because
+	// the site's robots.txt disallows it
+	StatusDisallowed Status = "disallowed"
+
+	// StatusFetched means the URL's page was fetched and parsed
+	StatusFetched Status = "fetched"
+
+	// StatusFailed means the URL's page could not be fetched
+	StatusFailed Status = "failed"
+
+	// StatusBroken means the URL is an asset that could not be fetched
+	StatusBroken Status = "broken"
 )
 
 type Node struct {
 	// URL of the Node
 	url string
+
+	// status of the Node's URL. It is empty until a status is set
+	status Status
+
+	// canonical is the URL of the Node's canonical page, if it declares one
+	canonical string
+
+	// noIndex is true if the Node's page asks crawlers not to index it
+	noIndex bool
+
+	// depth is the number of clicks it takes to get to the Node's URL
+	// from the crawler's starting URL
+	depth int
+
+	// title is the title of the Node's page, if it has been fetched
+	title string
+
+	// statusCode is the HTTP status code the Node's URL was served with
+	statusCode int
+
+	// contentType is the media type of the Node's URL e.g "text/html"
+	contentType string
+
+	// size is the number of bytes the Node's URL was served with
+	size int64
+
+	// latency is how long it took to fetch the Node's URL
+	latency time.Duration
+
+	// discoveredFrom is the URL of the page the Node's URL was first found on
+	discoveredFrom string
+
+	// discoveredAt is when the Node was added to the graph
+	discoveredAt time.Time
+
+	// fetchedAt is when the Node's URL was fetched
+	fetchedAt time.Time
+}
+
+// Fetch describes the outcome of fetching a node's URL
+type Fetch struct {
+	// StatusCode is the HTTP status code of the response. It is 0 if there was no response
+	StatusCode int
+
+	// ContentType is the media type of the response e.g "text/html"
+	ContentType string
+
+	// Size is the number of bytes the response was served with
+	Size int64
+
+	// Latency is how long it took to fetch the URL
+	Latency time.Duration
+
+	// FetchedAt is when the URL was fetched
+	FetchedAt time.Time
+}
+
+// Kind describes how one node leads to another
+type Kind string
+
+const (
+	// KindHyperlink is a link a visitor can follow from one page to another
+	KindHyperlink Kind = "hyperlink"
+
+	// KindRedirect is a redirect from one URL to another
+	KindRedirect Kind = "redirect"
+
+	// KindCanonical points from a page to the canonical URL it declares
+	KindCanonical Kind = "canonical"
+
+	// KindAsset points from a page to an asset it loads e.g an image, script or stylesheet
+	KindAsset Kind = "asset"
+
+	// KindHreflang points from a page to a translation of it declared with a
+	// <link rel="alternate" hreflang="..."> element
+	KindHreflang Kind = "hreflang"
+
+	// KindForm points from a page to the target of a form on it
+	KindForm Kind = "form"
+)
+
+// kindOf returns the kind of edge a link is stored as
+func kindOf(link page.Link) Kind {
+	switch link.Kind {
+	case page.KindRedirect:
+		return KindRedirect
+	case page.KindCanonical:
+		return KindCanonical
+	case page.KindAsset:
+		return KindAsset
+	case page.KindForm:
+		return KindForm
+	}
+
+	if link.Element == "link" && link.Hreflang != "" {
+		return KindHreflang
+	}
+
+	return KindHyperlink
+}
+
+// Edge is a link from one node to another. A page that links to the same
+// URL several times in the same way has a single edge to it
+type Edge struct {
+	// From is the URL of the node the link was found on
+	From string
+
+	// To is the URL of the node the link points to
+	To string
+
+	// Kind describes how the start node leads to the end node
+	Kind Kind
+
+	// Weight is the number of times the link was found on the start node
+	Weight int
+
+	// NoFollow is the number of times the link was found with rel=nofollow. The
+	// link is followed, and passes link equity, if it is less than the weight
+	NoFollow int
+
+	// Texts are the distinct anchor texts the link was found with, in the order they were found
+	Texts []string
+
+	// Link describes the first occurrence of the link. Its URL is the URL the link
+	// was found with, which may not be normalized like the node's URL
+	Link page.Link
+}
+
+// edgeKey identifies an edge by the IDs of its start node, end node and kind,
+// so links found several times are stored once
+type edgeKey struct {
+	from uint32
+	to   uint32
+	kind uint32
+}
+
+// edge is how an Edge is stored in the graph. Nodes are referred to by ID,
+// and strings by their ID in the graph's interner
+type edge struct {
+	from   uint32
+	to     uint32
+	kind   uint32
+	weight uint32
+
+	// noFollow is the number of times the link was found with rel=nofollow
+	noFollow uint32
+
+	// text is the first anchor text the link was found with. Further
+	// texts are rare, so they are kept in the graph's moreTexts
+	text uint32
+
+	link link
+}
+
+// targetURL is stored as the URL of a link whose URL is the URL of its edge's
+// target node, so the URL isn't stored a second time in the graph's interner
+const targetURL = ^uint32(0)
+
+// link is how the page.Link of an Edge is stored in the graph. Strings are
+// referred to by their ID in the graph's interner
+type link struct {
+	url       uint32
+	text      uint32
+	title     uint32
+	rel       uint32
+	hreflang  uint32
+	element   uint32
+	attribute uint32
+	kind      uint32
+	position  int32
+}
+
+// shardCount is the number of shards node metadata is split into, so
+// different nodes can be updated at the same time
+const shardCount = 16
+
+// shard holds the metadata of every node whose ID leaves a particular remainder
+// when divided by shardCount, at the position of the ID divided by shardCount
+type shard struct {
+	nodes []Node
+	mu    sync.RWMutex
 }
 
 type Graph struct {
-	// nodes is our collection of visited URLs
-	nodes map[string]*Node
+	// Seed is the URL the crawl that built the graph started from. It is saved
+	// with the graph, so reports on a loaded graph start from the same page
+	Seed string
+
+	// Normalization configures how URLs are normalized before they are stored or
+	// looked up, so different spellings of a URL refer to the same node
+	Normalization urlnorm.Options
+
+	// ids holds the ID of every node, keyed by URL. IDs are the positions
+	// of nodes in urls, and are assigned in the order nodes are added
+	ids map[string]uint32
+
+	// urls holds the URL of every node, by ID
+	urls []string
 
-	// edges represents the link between nodes
-	edges map[Node][]*Node
+	// nodesMu protects ids and urls. It is held while a node is added to its shard
+	nodesMu sync.RWMutex
 
-	// mu is a mutex that protects our Graph for concurrent use
-	mu sync.RWMutex
+	// shards hold the metadata of our collection of visited URLs
+	shards [shardCount]shard
+
+	// edges represents the links between nodes
+	edges []edge
+
+	// outlinks holds the positions in edges of the edges from each node, by node ID
+	outlinks [][]uint32
+
+	// inlinks is a reverse index of outlinks, by the ID of the end node
+	inlinks [][]uint32
+
+	// edgeIndex holds the position of every edge in edges. Snapshots are
+	// taken without it, so it is built when an edge is first added to one
+	edgeIndex map[edgeKey]uint32
+
+	// moreTexts holds the anchor texts of edges found with more than one, after the first
+	moreTexts map[uint32][]uint32
+
+	// edgesMu protects edges, outlinks, inlinks, edgeIndex and moreTexts.
+	// When several locks are held, nodesMu is locked first, then shards in order and then edgesMu
+	edgesMu sync.RWMutex
+
+	// strings holds the strings edges refer to. It is shared with snapshots of the graph
+	strings *interner
 }
 
 // NewGraph initializes a new Graph
 func NewGraph() *Graph {
 	return &Graph{
-		nodes: make(map[string]*Node),
-		edges: make(map[Node][]*Node),
+		ids:       make(map[string]uint32),
+		edgeIndex: make(map[edgeKey]uint32),
+		moreTexts: make(map[uint32][]uint32),
+		strings:   newInterner(),
+	}
+}
+
+// key returns the normalized URL a node is stored under.
+// URLs that can't be normalized are stored as they are
+func (g *Graph) key(url string) string {
+	normalized, err := urlnorm.Normalize(url, g.Normalization)
+
+	if err != nil {
+		return url
 	}
+
+	return normalized
 }
 
-// AddNode adds a node to the graph
+// id returns the ID of the node with a particular URL and whether the node exists
+func (g *Graph) id(url string) (uint32, bool) {
+	url = g.key(url)
+
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	id, exists := g.ids[url]
+
+	return id, exists
+}
+
+// shard returns the shard holding the metadata of the node with an ID
+func (g *Graph) shard(id uint32) *shard {
+	return &g.shards[id%shardCount]
+}
+
+// AddNode adds a node to the graph, recording when it was discovered
 func (g *Graph) AddNode(url string) {
-	if g.HasNode(url) {
-		return
+	g.addNode(Node{url: g.key(url), discoveredAt: time.Now()})
+}
+
+// addNode adds a node with its metadata to the graph if there is no node with its URL,
+// and returns the ID of the node with its URL
+func (g *Graph) addNode(node Node) uint32 {
+	g.nodesMu.Lock()
+	defer g.nodesMu.Unlock()
+
+	if id, exists := g.ids[node.url]; exists {
+		return id
+	}
+
+	id := uint32(len(g.urls))
+	g.ids[node.url] = id
+	g.urls = append(g.urls, node.url)
+
+	// Nodes are added to their shard in the order of their IDs
+	shard := g.shard(id)
+	shard.mu.Lock()
+	shard.nodes = append(shard.nodes, node)
+	shard.mu.Unlock()
+
+	return id
+}
+
+// update changes the metadata of the node with a particular URL,
+// and returns whether the node exists
+func (g *Graph) update(url string, change func(node *Node)) bool {
+	id, exists := g.id(url)
+
+	if !exists {
+		return false
 	}
 
-	node := &Node{url}
+	shard := g.shard(id)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
 
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	change(&shard.nodes[id/shardCount])
+	return true
+}
+
+// read returns a copy of the node with a particular URL and whether the node exists
+func (g *Graph) read(url string) (Node, bool) {
+	id, exists := g.id(url)
+
+	if !exists {
+		return Node{}, false
+	}
+
+	shard := g.shard(id)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
 
-	g.nodes[url] = node
+	return shard.nodes[id/shardCount], true
 }
 
-// AddEdge adds an edge to the graph
+// AddEdge adds a hyperlink edge to the graph, or adds to its weight if it already exists
 func (g *Graph) AddEdge(startURL, endURL string) error {
-	g.mu.RLock()
-	startNode := g.nodes[startURL]
-	endNode := g.nodes[endURL]
-	g.mu.RUnlock()
+	return g.addEdge(startURL, endURL, page.Link{URL: endURL})
+}
 
-	if startNode == nil {
+// AddLink adds an edge for a link found on the page with a particular URL to the graph.
+// If the page already has an edge of the same kind to the link's URL, its weight is
+// added to and the link's anchor text is recorded instead
+func (g *Graph) AddLink(startURL string, link page.Link) error {
+	return g.addEdge(startURL, link.URL, link)
+}
+
+// addEdge adds an edge described by a link to the graph, or adds to the weight of an existing edge
+func (g *Graph) addEdge(startURL, endURL string, link page.Link) error {
+	noFollow := 0
+
+	if link.NoFollow() {
+		noFollow = 1
+	}
+
+	return g.addWeightedEdge(startURL, endURL, kindOf(link), 1, noFollow, []string{link.Text}, link)
+}
+
+// addWeightedEdge adds an edge of a kind with a weight, the part of the weight that is
+// rel=nofollow and a list of anchor texts to the graph, or adds them to an existing edge
+func (g *Graph) addWeightedEdge(startURL, endURL string, kind Kind, weight, noFollow int, texts []string, link page.Link) error {
+	from, exists := g.id(startURL)
+
+	if !exists {
 		return fmt.Errorf("failed to add edge, no node found for %v", startURL)
 	}
 
-	if endNode == nil {
+	to, exists := g.id(endURL)
+
+	if !exists {
 		return fmt.Errorf("failed to add edge no node found for %v", endURL)
 	}
 
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	g.insertEdge(from, to, kind, weight, noFollow, texts, link)
+
+	return nil
+}
+
+// insertEdge adds an edge between the nodes with two IDs to the graph, or adds the weight,
+// nofollow weight and texts to an existing edge
+func (g *Graph) insertEdge(from, to uint32, kind Kind, weight, noFollow int, texts []string, link page.Link) {
+	// Strings are interned before the edges are locked, so adding edges waits on the lock for less time
+	stored := g.internLink(link, to)
+	key := edgeKey{from: from, to: to, kind: g.strings.intern(string(kind))}
+	textIDs := make([]uint32, len(texts))
+
+	for i, text := range texts {
+		textIDs[i] = g.strings.intern(text)
+	}
+
+	g.edgesMu.Lock()
+	defer g.edgesMu.Unlock()
+
+	g.indexEdges()
+
+	position, exists := g.edgeIndex[key]
+
+	if !exists {
+		position = uint32(len(g.edges))
+		g.edges = append(g.edges, edge{from: from, to: to, kind: key.kind, link: stored})
+		g.outlinks = appendAt(g.outlinks, from, position)
+		g.inlinks = appendAt(g.inlinks, to, position)
+		g.edgeIndex[key] = position
+	}
+
+	g.edges[position].weight += uint32(weight)
+	g.edges[position].noFollow += uint32(noFollow)
+
+	for _, text := range textIDs {
+		g.addText(position, text)
+	}
+}
+
+// internLink returns a link to the node with an ID as it is stored in the graph
+func (g *Graph) internLink(l page.Link, to uint32) link {
+	g.nodesMu.RLock()
+	url := g.urls[to]
+	g.nodesMu.RUnlock()
+
+	stored := link{
+		url:       targetURL,
+		text:      g.strings.intern(l.Text),
+		title:     g.strings.intern(l.Title),
+		rel:       g.strings.intern(strings.Join(l.Rel, " ")),
+		hreflang:  g.strings.intern(l.Hreflang),
+		element:   g.strings.intern(l.Element),
+		attribute: g.strings.intern(l.Attribute),
+		kind:      g.strings.intern(string(l.Kind)),
+		position:  int32(l.Position),
+	}
+
+	if l.URL != url {
+		stored.url = g.strings.intern(l.URL)
+	}
+
+	return stored
+}
+
+// indexEdges builds the graph's edge index if it doesn't have one.
+// The graph's edges must be locked for writing
+func (g *Graph) indexEdges() {
+	if g.edgeIndex != nil {
+		return
+	}
+
+	g.edgeIndex = make(map[edgeKey]uint32, len(g.edges))
+
+	for position, edge := range g.edges {
+		g.edgeIndex[edgeKey{from: edge.from, to: edge.to, kind: edge.kind}] = uint32(position)
+	}
+}
+
+// appendAt appends a value to the list at a position in a list of lists, adding empty lists up to it
+func appendAt(lists [][]uint32, position uint32, value uint32) [][]uint32 {
+	for uint32(len(lists)) <= position {
+		lists = append(lists, nil)
+	}
+
+	lists[position] = append(lists[position], value)
+
+	return lists
+}
+
+// addText records an anchor text of the edge at a position if it isn't empty or already recorded.
+// The graph's edges must be locked for writing
+func (g *Graph) addText(position uint32, text uint32) {
+	edge := &g.edges[position]
+
+	if text == 0 || edge.text == text {
+		return
+	}
+
+	if edge.text == 0 {
+		edge.text = text
+		return
+	}
+
+	for _, existing := range g.moreTexts[position] {
+		if existing == text {
+			return
+		}
+	}
+
+	g.moreTexts[position] = append(g.moreTexts[position], text)
+}
+
+// SetStatus sets the status of the node with a particular URL
+func (g *Graph) SetStatus(url string, status Status) error {
+	if !g.update(url, func(node *Node) { node.status = status }) {
+		return fmt.Errorf("failed to set status, no node found for %v", url)
+	}
 
-	g.edges[*startNode] = append(g.edges[*startNode], endNode)
 	return nil
 }
 
+// Status returns the status of the node with a particular URL
+// and whether the node exists
+func (g *Graph) Status(url string) (Status, bool) {
+	node, exists := g.read(url)
+
+	return node.status, exists
+}
+
 // HasNode checks if a node with a particular URL exists in the graph
 func (g *Graph) HasNode(url string) bool {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
-	_, exists := g.nodes[url]
+	_, exists := g.id(url)
 
 	return exists
 }
+
+// SetCanonical records the canonical URL declared by the node with a particular URL
+func (g *Graph) SetCanonical(url, canonicalURL string) error {
+	canonicalURL = g.key(canonicalURL)
+
+	if !g.update(url, func(node *Node) { node.canonical = canonicalURL }) {
+		return fmt.Errorf("failed to set canonical url, no node found for %v", url)
+	}
+
+	return nil
+}
+
+// Canonical returns the canonical URL of the node with a particular URL.
+// Duplicate pages share the same canonical URL, so they can be collapsed into it.
+// The node's own URL is returned if it doesn't declare a canonical URL
+func (g *Graph) Canonical(url string) string {
+	node, exists := g.read(url)
+
+	if !exists || node.canonical == "" {
+		return g.key(url)
+	}
+
+	return node.canonical
+}
+
+// SetNoIndex records whether the page of the node with a particular URL asks crawlers
+// not to index it, with a robots <meta> tag or X-Robots-Tag header
+func (g *Graph) SetNoIndex(url string, noIndex bool) error {
+	if !g.update(url, func(node *Node) { node.noIndex = noIndex }) {
+		return fmt.Errorf("failed to set noindex, no node found for %v", url)
+	}
+
+	return nil
+}
+
+// SetDepth records the number of clicks it takes to get to the node with a
+// particular URL from the crawler's starting URL
+func (g *Graph) SetDepth(url string, depth int) error {
+	if !g.update(url, func(node *Node) { node.depth = depth }) {
+		return fmt.Errorf("failed to set depth, no node found for %v", url)
+	}
+
+	return nil
+}
+
+// SetTitle records the title of the page of the node with a particular URL
+func (g *Graph) SetTitle(url, title string) error {
+	if !g.update(url, func(node *Node) { node.title = title }) {
+		return fmt.Errorf("failed to set title, no node found for %v", url)
+	}
+
+	return nil
+}
+
+// SetFetch records the outcome of fetching the URL of the node with a particular URL
+func (g *Graph) SetFetch(url string, fetch Fetch) error {
+	updated := g.update(url, func(node *Node) {
+		node.statusCode = fetch.StatusCode
+		node.contentType = fetch.ContentType
+		node.size = fetch.Size
+		node.latency = fetch.Latency
+		node.fetchedAt = fetch.FetchedAt
+	})
+
+	if !updated {
+		return fmt.Errorf("failed to set fetch, no node found for %v", url)
+	}
+
+	return nil
+}
+
+// SetDiscoveredFrom records the URL of the page the node with a particular URL was first found on
+func (g *Graph) SetDiscoveredFrom(url, fromURL string) error {
+	fromURL = g.key(fromURL)
+
+	if !g.update(url, func(node *Node) { node.discoveredFrom = fromURL }) {
+		return fmt.Errorf("failed to set discovered from url, no node found for %v", url)
+	}
+
+	return nil
+}
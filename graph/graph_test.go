@@ -2,7 +2,10 @@ package graph
 
 import (
 	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAddNode(t *testing.T) {
@@ -17,6 +20,16 @@ func TestAddNode(t *testing.T) {
 	}
 }
 
+func TestAddNodeNormalization(t *testing.T) {
+	g := NewGraph()
+
+	g.AddNode("https://Example.com:443/savings/#rates")
+
+	if !g.HasNode("https://example.com/savings") {
+		t.Fatalf("expected graph to store the normalized url")
+	}
+}
+
 func TestAddEdge(t *testing.T) {
 	g := NewGraph()
 
@@ -54,3 +67,270 @@ func TestAddEdgeError(t *testing.T) {
 		t.Fatalf("expected error message %v, got %v", expected, got)
 	}
 }
+
+func TestAddLink(t *testing.T) {
+	g := NewGraph()
+
+	g.AddNode("https://example.com")
+	g.AddNode("https://example.com/about")
+	g.AddNode("https://example.com/savings")
+	g.AddNode("https://example.com/logo.png")
+
+	links := []struct {
+		from string
+		link page.Link
+	}{
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/savings", Text: "Savings", Position: 0}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/savings", Text: "Open an account", Rel: []string{"nofollow"}, Position: 1}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/savings", Text: "Savings", Position: 2}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/logo.png", Kind: page.KindAsset, Position: 3}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com/savings/", Text: "savings"}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com", Text: "Home", Position: 1}},
+	}
+
+	for _, l := range links {
+		if err := g.AddLink(l.from, l.link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	// Links found several times on a page are stored once
+	edges := g.Inlinks("https://example.com/savings")
+
+	if len(edges) != 2 || g.EdgeCount() != 4 {
+		t.Fatalf("expected 2 links to savings and 4 edges, got %v and %v", len(edges), g.EdgeCount())
+	}
+
+	if edges[0].Weight != 3 || strings.Join(edges[0].Texts, ",") != "Savings,Open an account" || edges[0].Link.Position != 0 {
+		t.Fatalf("expected the home page link to savings to have a weight of 3, got %+v", edges[0])
+	}
+
+	edges = g.LinksWithText("https://example.com/savings", " OPEN AN account ")
+
+	if len(edges) != 1 || edges[0].From != "https://example.com" {
+		t.Fatalf("expected a link to savings from the home page, got %+v", edges)
+	}
+
+	edges = g.LinksWithText("https://example.com/savings", " SAVINGS ")
+
+	if len(edges) != 2 || edges[0].From != "https://example.com" || edges[1].From != "https://example.com/about" {
+		t.Fatalf("expected links to savings from the home and about pages, got %+v", edges)
+	}
+
+	// The nofollow link duplicates a followed link, so the edge is nofollow once out of its 3 links
+	edges = g.NoFollowLinks()
+
+	if len(edges) != 1 || edges[0].To != "https://example.com/savings" || edges[0].NoFollow != 1 || edges[0].Texts[1] != "Open an account" {
+		t.Fatalf("expected 1 nofollow link, got %+v", edges)
+	}
+
+	if !passesEquity(edges[0]) {
+		t.Fatalf("expected a link found without rel=nofollow to pass link equity, got %+v", edges[0])
+	}
+}
+
+func TestNoFollowLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		rels     [][]string
+		noFollow int
+		equity   bool
+	}{
+		{name: "followed then nofollow", rels: [][]string{nil, {"nofollow"}}, noFollow: 1, equity: true},
+		{name: "nofollow then followed", rels: [][]string{{"nofollow"}, nil}, noFollow: 1, equity: true},
+		{name: "only nofollow", rels: [][]string{{"nofollow"}, {"nofollow", "noopener"}}, noFollow: 2, equity: false},
+		{name: "only followed", rels: [][]string{nil, {"noopener"}}, noFollow: 0, equity: true},
+	}
+
+	for _, tc := range tests {
+		g := NewGraph()
+		g.AddNode("https://example.com")
+		g.AddNode("https://example.com/savings")
+
+		for i, rel := range tc.rels {
+			g.AddLink("https://example.com", page.Link{URL: "https://example.com/savings", Rel: rel, Position: i})
+		}
+
+		edges := g.Outlinks("https://example.com")
+
+		if len(edges) != 1 || edges[0].NoFollow != tc.noFollow || passesEquity(edges[0]) != tc.equity {
+			t.Errorf("%v: expected an edge with %v nofollow links that passes equity: %v, got %+v", tc.name, tc.noFollow, tc.equity, edges)
+		}
+
+		if found := len(g.NoFollowLinks()) == 1; found != (tc.noFollow > 0) {
+			t.Errorf("%v: expected the edge to be a nofollow link: %v", tc.name, tc.noFollow > 0)
+		}
+	}
+}
+
+func TestEdgeKinds(t *testing.T) {
+	g := NewGraph()
+
+	for _, url := range []string{"https://example.com", "https://example.com/fr", "https://example.com/old", "https://example.com/logo.png", "https://example.com/search"} {
+		g.AddNode(url)
+	}
+
+	links := []page.Link{
+		{URL: "https://example.com/fr", Element: "a", Hreflang: "fr"},
+		{URL: "https://example.com/fr", Element: "link", Hreflang: "fr", Rel: []string{"alternate"}},
+		{URL: "https://example.com/logo.png", Kind: page.KindAsset},
+		{URL: "https://example.com/search", Kind: page.KindForm},
+		{URL: "https://example.com/old", Kind: page.KindCanonical},
+	}
+
+	for i, link := range links {
+		link.Position = i
+
+		if err := g.AddLink("https://example.com", link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	g.AddLink("https://example.com/old", page.Link{URL: "https://example.com", Kind: page.KindRedirect})
+
+	// Links of different kinds to the same URL are separate edges
+	if g.OutDegree("https://example.com") != 5 {
+		t.Fatalf("expected 5 edges from the home page, got %v", g.OutDegree("https://example.com"))
+	}
+
+	tests := []struct {
+		kind Kind
+		to   string
+	}{
+		{kind: KindHyperlink, to: "https://example.com/fr"},
+		{kind: KindHreflang, to: "https://example.com/fr"},
+		{kind: KindAsset, to: "https://example.com/logo.png"},
+		{kind: KindForm, to: "https://example.com/search"},
+		{kind: KindCanonical, to: "https://example.com/old"},
+		{kind: KindRedirect, to: "https://example.com"},
+	}
+
+	for _, tc := range tests {
+		edges := g.EdgesOfKind(tc.kind)
+
+		if len(edges) != 1 || edges[0].To != tc.to {
+			t.Errorf("expected 1 %v edge to %v, got %+v", tc.kind, tc.to, edges)
+		}
+	}
+
+	if edges := g.EdgesOfKind(KindHyperlink, KindRedirect); len(edges) != 2 {
+		t.Errorf("expected 2 hyperlink and redirect edges, got %+v", edges)
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	g := NewGraph()
+
+	url := "https://example.com/admin"
+
+	g.AddNode(url)
+
+	err := g.SetStatus(url, StatusDisallowed)
+
+	if err != nil {
+		t.Fatalf("set status error: %v", err)
+	}
+
+	status, exists := g.Status(url)
+
+	if !exists {
+		t.Fatalf("expected graph to have node %v", url)
+	}
+
+	if status != StatusDisallowed {
+		t.Fatalf("expected node status to be %v, got %v", StatusDisallowed, status)
+	}
+
+	err = g.SetStatus("https://example.com/missing", StatusDisallowed)
+
+	if err == nil {
+		t.Fatalf("expected set status operation to fail")
+	}
+}
+
+func TestSetCanonical(t *testing.T) {
+	g := NewGraph()
+
+	canonicalURL := "https://example.com/cards"
+	duplicateURL := "https://example.com/cards?ref=footer"
+
+	g.AddNode(canonicalURL)
+	g.AddNode(duplicateURL)
+
+	err := g.SetCanonical(duplicateURL, canonicalURL)
+
+	if err != nil {
+		t.Fatalf("set canonical error: %v", err)
+	}
+
+	// Duplicate pages collapse into their canonical page
+	if g.Canonical(duplicateURL) != canonicalURL {
+		t.Fatalf("expected canonical url of %v to be %v, got %v", duplicateURL, canonicalURL, g.Canonical(duplicateURL))
+	}
+
+	if g.Canonical(canonicalURL) != canonicalURL {
+		t.Fatalf("expected canonical url of %v to be itself, got %v", canonicalURL, g.Canonical(canonicalURL))
+	}
+}
+
+func TestSetFetch(t *testing.T) {
+	g := NewGraph()
+
+	g.AddNode("https://example.com")
+	g.AddNode("https://example.com/about")
+
+	fetchedAt := time.Now()
+	fetch := Fetch{StatusCode: 200, ContentType: "text/html", Size: 2048, Latency: 120 * time.Millisecond, FetchedAt: fetchedAt}
+
+	if err := g.SetFetch("https://example.com/about/", fetch); err != nil {
+		t.Fatalf("set fetch error: %v", err)
+	}
+
+	if err := g.SetDiscoveredFrom("https://example.com/about", "https://example.com/"); err != nil {
+		t.Fatalf("set discovered from error: %v", err)
+	}
+
+	node, _ := g.Node("https://example.com/about")
+
+	if node.StatusCode() != 200 || node.ContentType() != "text/html" || node.Size() != 2048 || node.Latency() != 120*time.Millisecond {
+		t.Fatalf("unexpected fetch for the about page: %v %v %v %v", node.StatusCode(), node.ContentType(), node.Size(), node.Latency())
+	}
+
+	if !node.FetchedAt().Equal(fetchedAt) || node.DiscoveredAt().IsZero() || node.DiscoveredAt().After(fetchedAt) {
+		t.Fatalf("expected the about page to have been discovered before it was fetched, got %v and %v", node.DiscoveredAt(), node.FetchedAt())
+	}
+
+	if node.DiscoveredFrom() != "https://example.com" {
+		t.Fatalf("expected the about page to have been discovered from the home page, got %v", node.DiscoveredFrom())
+	}
+
+	if err := g.SetFetch("https://example.com/missing", fetch); err == nil {
+		t.Fatalf("expected set fetch operation to fail")
+	}
+}
+
+func TestLinkURL(t *testing.T) {
+	g := NewGraph()
+	g.AddNode("https://example.com")
+	g.AddNode("https://example.com/savings")
+
+	strings := len(g.strings.values)
+
+	// A link found with its target's URL doesn't store the URL again
+	g.AddLink("https://example.com", page.Link{URL: "https://example.com/savings"})
+
+	if len(g.strings.values) != strings+1 {
+		t.Fatalf("expected only the link's kind to be stored, got %v", g.strings.values[strings:])
+	}
+
+	// A link found with a URL that isn't normalized keeps it
+	g.AddLink("https://example.com/savings", page.Link{URL: "https://example.com/#top"})
+
+	tests := map[string]string{"https://example.com": "https://example.com/savings", "https://example.com/savings": "https://example.com/#top"}
+
+	for from, expected := range tests {
+		if edges := g.Outlinks(from); len(edges) != 1 || edges[0].Link.URL != expected {
+			t.Errorf("expected the link from %v to have the url %v, got %+v", from, expected, edges)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package graph
+
+import "sync"
+
+// interner stores strings that are repeated throughout the graph, such as anchor texts,
+// once and refers to them by ID. IDs are never reused, so an interner can be shared by a
+// graph and its snapshots. The empty string always has the ID 0
+type interner struct {
+	ids    map[string]uint32
+	values []string
+	mu     sync.RWMutex
+}
+
+// newInterner creates an interner holding the empty string
+func newInterner() *interner {
+	return &interner{ids: map[string]uint32{"": 0}, values: []string{""}}
+}
+
+// intern returns the ID of a string, storing it if it hasn't been seen before
+func (in *interner) intern(value string) uint32 {
+	in.mu.RLock()
+	id, exists := in.ids[value]
+	in.mu.RUnlock()
+
+	if exists {
+		return id
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if id, exists := in.ids[value]; exists {
+		return id
+	}
+
+	id = uint32(len(in.values))
+	in.ids[value] = id
+	in.values = append(in.values, value)
+
+	return id
+}
+
+// lookup returns a function that returns the string with an ID, and a function that
+// must be called once it is no longer used. Strings are looked up under a single lock
+func (in *interner) lookup() (value func(id uint32) string, done func()) {
+	in.mu.RLock()
+
+	return func(id uint32) string {
+		return in.values[id]
+	}, in.mu.RUnlock
+}
@@ -0,0 +1,249 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"io"
+	"time"
+)
+
+// jsonFormat identifies graphs saved in the JSON format
+const jsonFormat = "crwl-graph"
+
+// jsonRecord is a line of a graph saved in the JSON format.
+// The first record is a header, followed by a record for every node and then every edge
+type jsonRecord struct {
+	Format        string             `json:"format,omitempty"`
+	Version       int                `json:"version,omitempty"`
+	Seed          string             `json:"seed,omitempty"`
+	Normalization *jsonNormalization `json:"normalization,omitempty"`
+	Node          *jsonNode          `json:"node,omitempty"`
+	Edge          *jsonEdge          `json:"edge,omitempty"`
+}
+
+type jsonNormalization struct {
+	SortQuery         bool     `json:"sort_query,omitempty"`
+	StripQuery        []string `json:"strip_query,omitempty"`
+	KeepTrailingSlash bool     `json:"keep_trailing_slash,omitempty"`
+}
+
+type jsonNode struct {
+	URL            string     `json:"url"`
+	Status         Status     `json:"status,omitempty"`
+	Canonical      string     `json:"canonical,omitempty"`
+	Depth          int        `json:"depth"`
+	Title          string     `json:"title,omitempty"`
+	StatusCode     int        `json:"status_code,omitempty"`
+	ContentType    string     `json:"content_type,omitempty"`
+	Size           int64      `json:"size,omitempty"`
+	LatencyNS      int64      `json:"latency_ns,omitempty"`
+	DiscoveredFrom string     `json:"discovered_from,omitempty"`
+	DiscoveredAt   *time.Time `json:"discovered_at,omitempty"`
+	FetchedAt      *time.Time `json:"fetched_at,omitempty"`
+	NoIndex        bool       `json:"noindex,omitempty"`
+}
+
+type jsonEdge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Kind     Kind     `json:"kind"`
+	Weight   int      `json:"weight"`
+	NoFollow int      `json:"nofollow,omitempty"`
+	Texts    []string `json:"texts,omitempty"`
+	Link     jsonLink `json:"link"`
+}
+
+type jsonLink struct {
+	URL       string    `json:"url"`
+	Text      string    `json:"text,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Rel       []string  `json:"rel,omitempty"`
+	Hreflang  string    `json:"hreflang,omitempty"`
+	Element   string    `json:"element,omitempty"`
+	Attribute string    `json:"attribute,omitempty"`
+	Kind      page.Kind `json:"kind,omitempty"`
+	Position  int       `json:"position"`
+}
+
+// timePointer returns a pointer to a time, or nil for the zero time so it is left out
+func timePointer(value time.Time) *time.Time {
+	if value.IsZero() {
+		return nil
+	}
+
+	return &value
+}
+
+// timeValue returns the time a pointer points to, or the zero time for nil
+func timeValue(value *time.Time) time.Time {
+	if value == nil {
+		return time.Time{}
+	}
+
+	return *value
+}
+
+// SaveJSON writes the graph to a writer as newline delimited JSON: a header line with its seed,
+// followed by a line for every node and then a line for every edge.
+// Each line is written as it is encoded, so large graphs can be streamed.
+// Graphs are read back with Load
+func (g *Graph) SaveJSON(w io.Writer) error {
+	snapshot := g.Snapshot()
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+
+	header := jsonRecord{
+		Format:  jsonFormat,
+		Version: formatVersion,
+		Seed:    snapshot.Seed,
+		Normalization: &jsonNormalization{
+			SortQuery:         snapshot.Normalization.SortQuery,
+			StripQuery:        snapshot.Normalization.StripQuery,
+			KeepTrailingSlash: snapshot.Normalization.KeepTrailingSlash,
+		},
+	}
+
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+
+	for _, node := range snapshot.Nodes() {
+		record := jsonRecord{Node: &jsonNode{
+			URL:            node.url,
+			Status:         node.status,
+			Canonical:      node.canonical,
+			Depth:          node.depth,
+			Title:          node.title,
+			StatusCode:     node.statusCode,
+			ContentType:    node.contentType,
+			Size:           node.size,
+			LatencyNS:      int64(node.latency),
+			DiscoveredFrom: node.discoveredFrom,
+			DiscoveredAt:   timePointer(node.discoveredAt),
+			FetchedAt:      timePointer(node.fetchedAt),
+			NoIndex:        node.noIndex,
+		}}
+
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	for _, edge := range snapshot.Edges() {
+		record := jsonRecord{Edge: &jsonEdge{
+			From:     edge.From,
+			To:       edge.To,
+			Kind:     edge.Kind,
+			Weight:   edge.Weight,
+			NoFollow: edge.NoFollow,
+			Texts:    edge.Texts,
+			Link: jsonLink{
+				URL:       edge.Link.URL,
+				Text:      edge.Link.Text,
+				Title:     edge.Link.Title,
+				Rel:       edge.Link.Rel,
+				Hreflang:  edge.Link.Hreflang,
+				Element:   edge.Link.Element,
+				Attribute: edge.Link.Attribute,
+				Kind:      edge.Link.Kind,
+				Position:  edge.Link.Position,
+			},
+		}}
+
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// loadJSON reads a graph in the JSON format written by SaveJSON, one line at a time
+func loadJSON(r io.Reader) (*Graph, error) {
+	decoder := json.NewDecoder(r)
+
+	var header jsonRecord
+
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGraph, err)
+	}
+
+	if header.Format != jsonFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidGraph, header.Format)
+	}
+
+	if header.Version != formatVersion {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, header.Version)
+	}
+
+	g := NewGraph()
+	g.Seed = header.Seed
+
+	if header.Normalization != nil {
+		g.Normalization.SortQuery = header.Normalization.SortQuery
+		g.Normalization.StripQuery = header.Normalization.StripQuery
+		g.Normalization.KeepTrailingSlash = header.Normalization.KeepTrailingSlash
+	}
+
+	for line := 2; ; line++ {
+		var record jsonRecord
+
+		err := decoder.Decode(&record)
+
+		if err == io.EOF {
+			return g, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %v: %v", ErrInvalidGraph, line, err)
+		}
+
+		switch {
+		case record.Node != nil:
+			n := record.Node
+
+			g.addNode(Node{
+				url:            n.URL,
+				status:         n.Status,
+				canonical:      n.Canonical,
+				depth:          n.Depth,
+				title:          n.Title,
+				statusCode:     n.StatusCode,
+				contentType:    n.ContentType,
+				size:           n.Size,
+				latency:        time.Duration(n.LatencyNS),
+				discoveredFrom: n.DiscoveredFrom,
+				discoveredAt:   timeValue(n.DiscoveredAt),
+				fetchedAt:      timeValue(n.FetchedAt),
+				noIndex:        n.NoIndex,
+			})
+		case record.Edge != nil:
+			e := record.Edge
+
+			link := page.Link{
+				URL:       e.Link.URL,
+				Text:      e.Link.Text,
+				Title:     e.Link.Title,
+				Rel:       e.Link.Rel,
+				Hreflang:  e.Link.Hreflang,
+				Element:   e.Link.Element,
+				Attribute: e.Link.Attribute,
+				Kind:      e.Link.Kind,
+				Position:  e.Link.Position,
+			}
+
+			from, fromExists := g.ids[e.From]
+			to, toExists := g.ids[e.To]
+
+			if !fromExists || !toExists {
+				return nil, fmt.Errorf("%w: line %v: edge from %v to %v refers to a missing node", ErrInvalidGraph, line, e.From, e.To)
+			}
+
+			g.insertEdge(from, to, e.Kind, e.Weight, e.NoFollow, e.Texts, link)
+		default:
+			return nil, fmt.Errorf("%w: line %v is neither a node nor an edge", ErrInvalidGraph, line)
+		}
+	}
+}
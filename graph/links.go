@@ -0,0 +1,152 @@
+package graph
+
+// linkGraph is an index of the links between the pages of a graph
+type linkGraph struct {
+	// urls are the URLs of the pages in the graph, sorted
+	urls []string
+
+	// positions are the positions of the pages in urls, keyed by URL
+	positions map[string]int
+
+	// outlinks are the positions of the pages each page links to,
+	// with a position for every edge
+	outlinks [][]int
+
+	// inlinks are the positions of the pages that link to each page,
+	// with a position for every edge
+	inlinks [][]int
+
+	// edges are the edges of each page's outlinks, in the same order
+	edges [][]Edge
+}
+
+// linkGraph returns an index of the edges between the graph's pages that match a condition.
+// Nodes that are only linked to as assets are not pages, so they are left out
+func (g *Graph) linkGraph(include func(edge Edge) bool) linkGraph {
+	snapshot := g.Snapshot()
+
+	index := linkGraph{positions: make(map[string]int)}
+
+	for _, node := range snapshot.Nodes() {
+		inlinks := snapshot.Inlinks(node.url)
+		isPage := len(inlinks) == 0
+
+		for _, edge := range inlinks {
+			if edge.Kind != KindAsset {
+				isPage = true
+				break
+			}
+		}
+
+		if isPage {
+			index.positions[node.url] = len(index.urls)
+			index.urls = append(index.urls, node.url)
+		}
+	}
+
+	index.outlinks = make([][]int, len(index.urls))
+	index.inlinks = make([][]int, len(index.urls))
+	index.edges = make([][]Edge, len(index.urls))
+
+	for from, url := range index.urls {
+		for _, edge := range snapshot.Outlinks(url) {
+			to, isPage := index.positions[edge.To]
+
+			if !isPage || !include(edge) {
+				continue
+			}
+
+			index.outlinks[from] = append(index.outlinks[from], to)
+			index.inlinks[to] = append(index.inlinks[to], from)
+			index.edges[from] = append(index.edges[from], edge)
+		}
+	}
+
+	return index
+}
+
+// clickable checks if an edge can be followed by a visitor to get to its target.
+// Only hyperlinks can be clicked, while redirects are followed without a click
+func clickable(edge Edge) bool {
+	return edge.Kind == KindHyperlink || edge.Kind == KindRedirect
+}
+
+// clicks returns the number of clicks it takes to follow an edge
+func clicks(edge Edge) int {
+	if edge.Kind == KindRedirect {
+		return 0
+	}
+
+	return 1
+}
+
+// search finds the smallest number of clicks it takes to get to each page in the index
+// from the page at a position. Redirects don't count as clicks.
+// It returns the number of clicks to each page, with -1 for pages that can't be
+// reached, and the edge each reached page was reached through
+func (index linkGraph) search(start int) (depths []int, via []Edge) {
+	depths = make([]int, len(index.urls))
+	via = make([]Edge, len(index.urls))
+
+	for i := range depths {
+		depths[i] = -1
+	}
+
+	depths[start] = 0
+
+	// Pages are searched a click at a time. Pages reached without a click join the
+	// frontier being searched, while pages that took one more click join the next one
+	frontier := []int{start}
+
+	for len(frontier) > 0 {
+		next := []int{}
+
+		for i := 0; i < len(frontier); i++ {
+			current := frontier[i]
+
+			for k, to := range index.outlinks[current] {
+				edge := index.edges[current][k]
+				depth := depths[current] + clicks(edge)
+
+				if depths[to] != -1 && depths[to] <= depth {
+					continue
+				}
+
+				depths[to] = depth
+				via[to] = edge
+
+				if depth == depths[current] {
+					frontier = append(frontier, to)
+				} else {
+					next = append(next, to)
+				}
+			}
+		}
+
+		frontier = next
+	}
+
+	return depths, via
+}
+
+// clickDepths returns the number of clicks it takes to get to every page in the index
+// that can be reached from a seed URL, keyed by URL
+func (index linkGraph) clickDepths(seed string) map[string]int {
+	clickDepths := make(map[string]int)
+
+	start, exists := index.positions[seed]
+
+	if !exists {
+		return clickDepths
+	}
+
+	depths, _ := index.search(start)
+
+	for i, depth := range depths {
+		if depth != -1 {
+			clickDepths[index.urls[i]] = depth
+		}
+	}
+
+	return clickDepths
+}
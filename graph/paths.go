@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrNoPath is returned when there's no chain of links from one page to another
+var ErrNoPath = errors.New("no path found")
+
+// ClickDepths returns the number of clicks it takes to get to every page
+// that can be reached from a seed URL, keyed by URL.
+// Links to assets and forms are not clicks, so assets have no click depth
+func (g *Graph) ClickDepths(seed string) map[string]int {
+	return g.linkGraph(clickable).clickDepths(g.key(seed))
+}
+
+// ShortestPath returns the edges of the chain of links from one page to another
+// that takes the fewest clicks. Redirects are part of the chain, but don't count
+// as clicks. ErrNoPath is returned if the page can't be reached
+func (g *Graph) ShortestPath(from, to string) ([]Edge, error) {
+	index := g.linkGraph(clickable)
+
+	start, exists := index.positions[g.key(from)]
+
+	if !exists {
+		return nil, fmt.Errorf("%w: no page found for %v", ErrNoPath, from)
+	}
+
+	end, exists := index.positions[g.key(to)]
+
+	if !exists {
+		return nil, fmt.Errorf("%w: no page found for %v", ErrNoPath, to)
+	}
+
+	depths, via := index.search(start)
+
+	if depths[end] == -1 {
+		return nil, fmt.Errorf("%w from %v to %v", ErrNoPath, from, to)
+	}
+
+	// Walk back from the end page to the start page
+	path := []Edge{}
+
+	for current := end; current != start; current = index.positions[via[current].From] {
+		path = append([]Edge{via[current]}, path...)
+	}
+
+	return path, nil
+}
+
+// DepthReport describes how many clicks it takes to get to a site's pages from a seed URL
+type DepthReport struct {
+	// Seed is the URL click depths are counted from
+	Seed string
+
+	// Threshold is the click depth pages are flagged as deep beyond
+	Threshold int
+
+	// Distribution is the number of pages at each click depth
+	Distribution map[int]int
+
+	// Deep are the URLs of pages deeper than the threshold, sorted
+	Deep []string
+
+	// Unreachable are the URLs of pages that can't be reached from the seed, sorted
+	Unreachable []string
+}
+
+// DepthReport returns the click-depth distribution of the graph's pages from a seed URL,
+// and flags pages that are deeper than a threshold. Pages aren't flagged if the threshold is 0
+func (g *Graph) DepthReport(seed string, threshold int) DepthReport {
+	index := g.linkGraph(clickable)
+	clickDepths := index.clickDepths(g.key(seed))
+
+	report := DepthReport{
+		Seed:         g.key(seed),
+		Threshold:    threshold,
+		Distribution: make(map[int]int),
+		Deep:         []string{},
+		Unreachable:  []string{},
+	}
+
+	for _, url := range index.urls {
+		depth, reachable := clickDepths[url]
+
+		if !reachable {
+			report.Unreachable = append(report.Unreachable, url)
+			continue
+		}
+
+		report.Distribution[depth]++
+
+		if threshold > 0 && depth > threshold {
+			report.Deep = append(report.Deep, url)
+		}
+	}
+
+	return report
+}
+
+// Write writes the report to a writer
+func (r DepthReport) Write(w io.Writer) error {
+	depths := []int{}
+
+	for depth := range r.Distribution {
+		depths = append(depths, depth)
+	}
+
+	sort.Ints(depths)
+
+	message := fmt.Sprintf("🪜 Click depth from %v:\n", r.Seed)
+
+	for _, depth := range depths {
+		message += fmt.Sprintf("\t %v clicks: %v pages\n", depth, r.Distribution[depth])
+	}
+
+	if len(r.Deep) > 0 {
+		message += fmt.Sprintf("🕳 %v pages are more than %v clicks deep:\n", len(r.Deep), r.Threshold)
+
+		for _, url := range r.Deep {
+			message += fmt.Sprintf("\t %v\n", url)
+		}
+	}
+
+	if len(r.Unreachable) > 0 {
+		message += fmt.Sprintf("🏝 %v pages can't be reached from %v:\n", len(r.Unreachable), r.Seed)
+
+		for _, url := range r.Unreachable {
+			message += fmt.Sprintf("\t %v\n", url)
+		}
+	}
+
+	_, err := io.WriteString(w, message)
+	return err
+}
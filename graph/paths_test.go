@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"github.com/darthchudi/crwl/page"
+	"strings"
+	"testing"
+)
+
+// newPathGraph returns a graph where the home page links to the about and
+// blog pages, the blog page links to a post with a nofollow link and loads
+// an image, the post links to a deep archive page, the about page links to
+// a careers page that redirects to a jobs page and a landing page isn't
+// linked to from anywhere
+func newPathGraph(t *testing.T) *Graph {
+	g := NewGraph()
+
+	urls := []string{
+		"https://example.com",
+		"https://example.com/about",
+		"https://example.com/blog",
+		"https://example.com/blog/post",
+		"https://example.com/blog/archive",
+		"https://example.com/logo.png",
+		"https://example.com/landing",
+		"https://example.com/careers",
+		"https://example.com/jobs",
+	}
+
+	for _, url := range urls {
+		g.AddNode(url)
+	}
+
+	links := []struct {
+		from string
+		link page.Link
+	}{
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/about", Text: "About"}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/blog", Text: "Blog", Position: 1}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com/blog", Text: "Our blog"}},
+		{from: "https://example.com/blog", link: page.Link{URL: "https://example.com/blog/post", Text: "Post", Rel: []string{"nofollow"}}},
+		{from: "https://example.com/blog", link: page.Link{URL: "https://example.com/logo.png", Kind: page.KindAsset, Position: 1}},
+		{from: "https://example.com/blog/post", link: page.Link{URL: "https://example.com/blog/archive", Text: "Archive"}},
+		{from: "https://example.com/landing", link: page.Link{URL: "https://example.com", Text: "Home"}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com/careers", Text: "Careers", Position: 1}},
+		{from: "https://example.com/careers", link: page.Link{URL: "https://example.com/jobs", Kind: page.KindRedirect}},
+	}
+
+	for _, l := range links {
+		if err := g.AddLink(l.from, l.link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+func TestClickDepths(t *testing.T) {
+	depths := newPathGraph(t).ClickDepths("https://example.com")
+
+	expected := map[string]int{
+		"https://example.com":              0,
+		"https://example.com/about":        1,
+		"https://example.com/blog":         1,
+		"https://example.com/blog/post":    2,
+		"https://example.com/blog/archive": 3,
+		"https://example.com/careers":      2,
+		"https://example.com/jobs":         2,
+	}
+
+	if len(depths) != len(expected) {
+		t.Fatalf("expected %v click depths, got %v", len(expected), depths)
+	}
+
+	for url, depth := range expected {
+		if depths[url] != depth {
+			t.Errorf("expected %v to be %v clicks deep, got %v", url, depth, depths[url])
+		}
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	g := newPathGraph(t)
+
+	path, err := g.ShortestPath("https://example.com", "https://example.com/blog/archive/")
+
+	if err != nil {
+		t.Fatalf("shortest path error: %v", err)
+	}
+
+	texts := []string{}
+
+	for _, edge := range path {
+		texts = append(texts, edge.Link.Text)
+	}
+
+	if strings.Join(texts, " > ") != "Blog > Post > Archive" || path[0].From != "https://example.com" {
+		t.Fatalf("expected path through the blog, got %+v", path)
+	}
+
+	// Redirects are part of the path
+	path, err = g.ShortestPath("https://example.com", "https://example.com/jobs")
+
+	if err != nil || len(path) != 3 || path[2].Link.Kind != page.KindRedirect {
+		t.Fatalf("expected path through the careers redirect, got %+v, %v", path, err)
+	}
+
+	if path, err := g.ShortestPath("https://example.com", "https://example.com"); err != nil || len(path) != 0 {
+		t.Fatalf("expected an empty path from a page to itself, got %v, %v", path, err)
+	}
+
+	if _, err := g.ShortestPath("https://example.com", "https://example.com/landing"); !errors.Is(err, ErrNoPath) {
+		t.Fatalf("expected no path to the landing page, got %v", err)
+	}
+
+	if _, err := g.ShortestPath("https://example.com", "https://example.com/logo.png"); !errors.Is(err, ErrNoPath) {
+		t.Fatalf("expected no path to an asset, got %v", err)
+	}
+}
+
+func TestDepthReport(t *testing.T) {
+	report := newPathGraph(t).DepthReport("https://example.com", 2)
+
+	if report.Distribution[0] != 1 || report.Distribution[1] != 2 || report.Distribution[2] != 3 || report.Distribution[3] != 1 {
+		t.Fatalf("unexpected click depth distribution: %v", report.Distribution)
+	}
+
+	if strings.Join(report.Deep, " ") != "https://example.com/blog/archive" {
+		t.Fatalf("expected the archive to be flagged as deep, got %v", report.Deep)
+	}
+
+	if strings.Join(report.Unreachable, " ") != "https://example.com/landing" {
+		t.Fatalf("expected the landing page to be unreachable, got %v", report.Unreachable)
+	}
+
+	var b bytes.Buffer
+
+	if err := report.Write(&b); err != nil {
+		t.Fatalf("write report error: %v", err)
+	}
+
+	if !strings.Contains(b.String(), "3 clicks: 1 pages") {
+		t.Fatalf("expected report to have the click depth distribution, got %v", b.String())
+	}
+}
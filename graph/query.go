@@ -0,0 +1,365 @@
+package graph
+
+import (
+	"github.com/darthchudi/crwl/page"
+	"sort"
+	"strings"
+	"time"
+)
+
+// URL returns the URL of the node
+func (n Node) URL() string {
+	return n.url
+}
+
+// Status returns the status of the node's URL. It is empty until a status is set
+func (n Node) Status() Status {
+	return n.status
+}
+
+// Canonical returns the URL of the node's canonical page, or an
+// empty string if it doesn't declare one
+func (n Node) Canonical() string {
+	return n.canonical
+}
+
+// NoIndex checks if the node's page asks crawlers not to index it
+func (n Node) NoIndex() bool {
+	return n.noIndex
+}
+
+// Depth returns the number of clicks it takes to get to the node's URL
+// from the crawler's starting URL
+func (n Node) Depth() int {
+	return n.depth
+}
+
+// Title returns the title of the node's page, or an empty string if it hasn't been fetched
+func (n Node) Title() string {
+	return n.title
+}
+
+// StatusCode returns the HTTP status code the node's URL was served with,
+// or 0 if it hasn't been fetched or there was no response
+func (n Node) StatusCode() int {
+	return n.statusCode
+}
+
+// ContentType returns the media type the node's URL was served with e.g "text/html"
+func (n Node) ContentType() string {
+	return n.contentType
+}
+
+// Size returns the number of bytes the node's URL was served with
+func (n Node) Size() int64 {
+	return n.size
+}
+
+// Latency returns how long it took to fetch the node's URL
+func (n Node) Latency() time.Duration {
+	return n.latency
+}
+
+// DiscoveredFrom returns the URL of the page the node's URL was first found on,
+// or an empty string for the crawler's starting URL
+func (n Node) DiscoveredFrom() string {
+	return n.discoveredFrom
+}
+
+// DiscoveredAt returns when the node was added to the graph
+func (n Node) DiscoveredAt() time.Time {
+	return n.discoveredAt
+}
+
+// FetchedAt returns when the node's URL was fetched, or the zero time if it hasn't been
+func (n Node) FetchedAt() time.Time {
+	return n.fetchedAt
+}
+
+// sortEdges sorts edges by the URL of their start node and the
+// position of their link on its page
+func sortEdges(edges []Edge) {
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+
+		return edges[i].Link.Position < edges[j].Link.Position
+	})
+}
+
+// materialize returns the Edge of the edge at a position, with its strings looked up
+// with a function. The graph's nodes and edges must be locked for reading
+func (g *Graph) materialize(position uint32, value func(id uint32) string) Edge {
+	stored := g.edges[position]
+
+	edge := Edge{
+		From:     g.urls[stored.from],
+		To:       g.urls[stored.to],
+		Kind:     Kind(value(stored.kind)),
+		Weight:   int(stored.weight),
+		NoFollow: int(stored.noFollow),
+		Texts:    []string{},
+		Link: page.Link{
+			URL:       g.urls[stored.to],
+			Text:      value(stored.link.text),
+			Title:     value(stored.link.title),
+			Hreflang:  value(stored.link.hreflang),
+			Element:   value(stored.link.element),
+			Attribute: value(stored.link.attribute),
+			Kind:      page.Kind(value(stored.link.kind)),
+			Position:  int(stored.link.position),
+		},
+	}
+
+	if stored.link.url != targetURL {
+		edge.Link.URL = value(stored.link.url)
+	}
+
+	if rel := value(stored.link.rel); rel != "" {
+		edge.Link.Rel = strings.Fields(rel)
+	}
+
+	if stored.text != 0 {
+		edge.Texts = append(edge.Texts, value(stored.text))
+	}
+
+	for _, text := range g.moreTexts[position] {
+		edge.Texts = append(edge.Texts, value(text))
+	}
+
+	return edge
+}
+
+// copyEdges returns a sorted list of the edges at a list of positions
+func (g *Graph) copyEdges(positions []uint32) []Edge {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+
+	value, done := g.strings.lookup()
+	defer done()
+
+	copied := make([]Edge, len(positions))
+
+	for i, position := range positions {
+		copied[i] = g.materialize(position, value)
+	}
+
+	sortEdges(copied)
+
+	return copied
+}
+
+// adjacent returns the positions of the edges in a list of lists
+// of edges for the node with a particular URL
+func (g *Graph) adjacent(lists func() [][]uint32, url string) []uint32 {
+	id, exists := g.id(url)
+
+	if !exists {
+		return nil
+	}
+
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+
+	adjacent := lists()
+
+	if id >= uint32(len(adjacent)) {
+		return nil
+	}
+
+	// Edges are only ever appended, so the list can be read once the lock is released
+	return adjacent[id][:len(adjacent[id]):len(adjacent[id])]
+}
+
+// Nodes returns a copy of every node in the graph, sorted by URL
+func (g *Graph) Nodes() []Node {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	nodes := make([]Node, 0, len(g.urls))
+
+	for i := range g.shards {
+		g.shards[i].mu.RLock()
+		nodes = append(nodes, g.shards[i].nodes...)
+		g.shards[i].mu.RUnlock()
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].url < nodes[j].url
+	})
+
+	return nodes
+}
+
+// Node returns a copy of the node with a particular URL and whether the node exists
+func (g *Graph) Node(url string) (Node, bool) {
+	return g.read(url)
+}
+
+// Edges returns every edge in the graph, sorted by the URL of their
+// start node and the position of their link on its page
+func (g *Graph) Edges() []Edge {
+	return g.findEdges(func(edge Edge) bool {
+		return true
+	})
+}
+
+// Outlinks returns the edges of the links on the node with a particular URL
+func (g *Graph) Outlinks(url string) []Edge {
+	return g.copyEdges(g.adjacent(func() [][]uint32 { return g.outlinks }, url))
+}
+
+// Inlinks returns the edges of every link to the node with a particular URL
+func (g *Graph) Inlinks(url string) []Edge {
+	return g.copyEdges(g.adjacent(func() [][]uint32 { return g.inlinks }, url))
+}
+
+// OutDegree returns the number of distinct links on the node with a particular URL
+func (g *Graph) OutDegree(url string) int {
+	return len(g.adjacent(func() [][]uint32 { return g.outlinks }, url))
+}
+
+// InDegree returns the number of distinct links to the node with a particular URL
+func (g *Graph) InDegree(url string) int {
+	return len(g.adjacent(func() [][]uint32 { return g.inlinks }, url))
+}
+
+// NodeCount returns the number of nodes in the graph
+func (g *Graph) NodeCount() int {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	return len(g.urls)
+}
+
+// EdgeCount returns the number of edges in the graph. Links found several times count once
+func (g *Graph) EdgeCount() int {
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+
+	return len(g.edges)
+}
+
+// Snapshot returns a copy of the graph at a single point in time.
+// The copy is unaffected by changes made to the graph after it is taken,
+// so reports built from several queries on it are consistent while a crawl
+// is still adding to the graph.
+// Lists that are only ever appended to are shared with the snapshot rather than copied
+func (g *Graph) Snapshot() *Graph {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	for i := range g.shards {
+		g.shards[i].mu.RLock()
+		defer g.shards[i].mu.RUnlock()
+	}
+
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+
+	snapshot := &Graph{
+		Seed:          g.Seed,
+		Normalization: g.Normalization,
+		ids:           make(map[string]uint32, len(g.ids)),
+		urls:          g.urls[:len(g.urls):len(g.urls)],
+		edges:         append([]edge{}, g.edges...),
+		outlinks:      shareLists(g.outlinks),
+		inlinks:       shareLists(g.inlinks),
+		moreTexts:     make(map[uint32][]uint32, len(g.moreTexts)),
+		strings:       g.strings,
+	}
+
+	for url, id := range g.ids {
+		snapshot.ids[url] = id
+	}
+
+	for i := range g.shards {
+		snapshot.shards[i].nodes = append([]Node{}, g.shards[i].nodes...)
+	}
+
+	for position, texts := range g.moreTexts {
+		snapshot.moreTexts[position] = texts[:len(texts):len(texts)]
+	}
+
+	return snapshot
+}
+
+// shareLists returns a copy of a list of lists that shares the inner lists. Their capacity
+// is limited to their length, so appending to either copy leaves the other unchanged
+func shareLists(lists [][]uint32) [][]uint32 {
+	shared := make([][]uint32, len(lists))
+
+	for i, list := range lists {
+		shared[i] = list[:len(list):len(list)]
+	}
+
+	return shared
+}
+
+// findEdges returns the edges that match a condition, sorted by the URL
+// of their start node and the position of their link on its page
+func (g *Graph) findEdges(match func(edge Edge) bool) []Edge {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+
+	value, done := g.strings.lookup()
+	defer done()
+
+	edges := []Edge{}
+
+	for position := range g.edges {
+		if edge := g.materialize(uint32(position), value); match(edge) {
+			edges = append(edges, edge)
+		}
+	}
+
+	sortEdges(edges)
+
+	return edges
+}
+
+// EdgesOfKind returns the edges in the graph of any of a list of kinds, sorted by the
+// URL of their start node and the position of their first link on its page
+func (g *Graph) EdgesOfKind(kinds ...Kind) []Edge {
+	return g.findEdges(func(edge Edge) bool {
+		for _, kind := range kinds {
+			if edge.Kind == kind {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// LinksWithText returns the edges of links to the node with a particular URL
+// that were found with an anchor text matching a text, ignoring case
+func (g *Graph) LinksWithText(url, text string) []Edge {
+	text = strings.Join(strings.Fields(text), " ")
+	edges := []Edge{}
+
+	for _, edge := range g.Inlinks(url) {
+		for _, edgeText := range edge.Texts {
+			if strings.EqualFold(edgeText, text) {
+				edges = append(edges, edge)
+				break
+			}
+		}
+	}
+
+	return edges
+}
+
+// NoFollowLinks returns the edges in the graph that were found as a rel=nofollow link at least once
+func (g *Graph) NoFollowLinks() []Edge {
+	return g.findEdges(func(edge Edge) bool {
+		return edge.NoFollow > 0
+	})
+}
@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"github.com/darthchudi/crwl/page"
+	"testing"
+)
+
+// newTestGraph returns a graph of a small site:
+// the home page links to the about and savings pages, and the
+// about page links to the savings page and back to the home page
+func newTestGraph(t *testing.T) *Graph {
+	g := NewGraph()
+
+	for _, url := range []string{"https://example.com", "https://example.com/about", "https://example.com/savings"} {
+		g.AddNode(url)
+	}
+
+	links := []struct {
+		from string
+		to   string
+	}{
+		{from: "https://example.com", to: "https://example.com/about"},
+		{from: "https://example.com", to: "https://example.com/savings"},
+		{from: "https://example.com/about", to: "https://example.com/savings"},
+		{from: "https://example.com/about", to: "https://example.com"},
+	}
+
+	for i, l := range links {
+		if err := g.AddLink(l.from, page.Link{URL: l.to, Position: i}); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+func TestNodes(t *testing.T) {
+	g := newTestGraph(t)
+	g.SetStatus("https://example.com/about", StatusDisallowed)
+
+	nodes := g.Nodes()
+
+	if len(nodes) != 3 || g.NodeCount() != 3 {
+		t.Fatalf("expected 3 nodes, got %v", len(nodes))
+	}
+
+	// Nodes are sorted by URL
+	if nodes[0].URL() != "https://example.com" || nodes[1].URL() != "https://example.com/about" {
+		t.Fatalf("expected nodes to be sorted by url, got %v and %v", nodes[0].URL(), nodes[1].URL())
+	}
+
+	if nodes[1].Status() != StatusDisallowed {
+		t.Fatalf("expected the about page to be disallowed, got %q", nodes[1].Status())
+	}
+
+	node, exists := g.Node("https://example.com/savings/")
+
+	if !exists || node.URL() != "https://example.com/savings" {
+		t.Fatalf("expected to find the savings node, got %v", node.URL())
+	}
+}
+
+func TestEdges(t *testing.T) {
+	g := newTestGraph(t)
+
+	if edges := g.Edges(); len(edges) != 4 || g.EdgeCount() != 4 {
+		t.Fatalf("expected 4 edges, got %v", len(edges))
+	}
+
+	outlinks := g.Outlinks("https://example.com/about")
+
+	if len(outlinks) != 2 || outlinks[0].To != "https://example.com/savings" || outlinks[1].To != "https://example.com" {
+		t.Fatalf("unexpected outlinks for the about page: %+v", outlinks)
+	}
+
+	inlinks := g.Inlinks("https://example.com/savings")
+
+	if len(inlinks) != 2 || inlinks[0].From != "https://example.com" || inlinks[1].From != "https://example.com/about" {
+		t.Fatalf("unexpected inlinks for the savings page: %+v", inlinks)
+	}
+
+	tests := []struct {
+		url       string
+		inDegree  int
+		outDegree int
+	}{
+		{url: "https://example.com", inDegree: 1, outDegree: 2},
+		{url: "https://example.com/about", inDegree: 1, outDegree: 2},
+		{url: "https://example.com/savings", inDegree: 2, outDegree: 0},
+		{url: "https://example.com/missing", inDegree: 0, outDegree: 0},
+	}
+
+	for _, tc := range tests {
+		if g.InDegree(tc.url) != tc.inDegree || g.OutDegree(tc.url) != tc.outDegree {
+			t.Errorf("expected %v to have in/out degree %v/%v, got %v/%v", tc.url, tc.inDegree, tc.outDegree, g.InDegree(tc.url), g.OutDegree(tc.url))
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	g := newTestGraph(t)
+
+	snapshot := g.Snapshot()
+
+	g.AddNode("https://example.com/cards")
+	g.AddEdge("https://example.com", "https://example.com/cards")
+	g.AddEdge("https://example.com", "https://example.com/about")
+	g.SetStatus("https://example.com", StatusBroken)
+
+	// Changes made after the snapshot was taken are not in it
+	if snapshot.NodeCount() != 3 || snapshot.EdgeCount() != 4 || snapshot.OutDegree("https://example.com") != 2 {
+		t.Fatalf("expected the snapshot to be unchanged, got %v nodes and %v edges", snapshot.NodeCount(), snapshot.EdgeCount())
+	}
+
+	if edges := snapshot.Outlinks("https://example.com"); edges[0].Weight != 1 {
+		t.Fatalf("expected the snapshot edge weight to be unchanged, got %v", edges[0].Weight)
+	}
+
+	if status, _ := snapshot.Status("https://example.com"); status != "" {
+		t.Fatalf("expected the snapshot node status to be unchanged, got %q", status)
+	}
+}
@@ -0,0 +1,237 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// RankOptions configures how PageRank and HITS scores are computed
+type RankOptions struct {
+	// Damping is the probability that a visitor follows a link on a page
+	// instead of jumping to a random page. It is only used by PageRank
+	Damping float64
+
+	// Iterations is the maximum number of iterations to compute scores with
+	Iterations int
+
+	// Tolerance stops the computation early once the total change in
+	// scores between two iterations is smaller than it
+	Tolerance float64
+}
+
+// DefaultRankOptions returns the options scores are usually computed with
+func DefaultRankOptions() RankOptions {
+	return RankOptions{Damping: 0.85, Iterations: 100, Tolerance: 1e-6}
+}
+
+// passesEquity checks if an edge passes link equity to its target.
+// Only hyperlinks and redirects do, unless every link they were found as is a rel=nofollow link
+func passesEquity(edge Edge) bool {
+	return clickable(edge) && edge.NoFollow < edge.Weight
+}
+
+// PageRank computes the PageRank of every page in the graph, keyed by URL.
+// Scores add up to 1. Links to assets and rel=nofollow links don't pass rank,
+// and the rank of pages without links is shared between every page
+func (g *Graph) PageRank(opts RankOptions) map[string]float64 {
+	index := g.linkGraph(passesEquity)
+	n := len(index.urls)
+	ranks := make(map[string]float64, n)
+
+	if n == 0 {
+		return ranks
+	}
+
+	scores := make([]float64, n)
+
+	for i := range scores {
+		scores[i] = 1 / float64(n)
+	}
+
+	for iteration := 0; iteration < opts.Iterations; iteration++ {
+		// Rank held by pages without links is shared between every page
+		dangling := 0.0
+
+		for i, outlinks := range index.outlinks {
+			if len(outlinks) == 0 {
+				dangling += scores[i]
+			}
+		}
+
+		next := make([]float64, n)
+		base := (1-opts.Damping)/float64(n) + opts.Damping*dangling/float64(n)
+
+		for i := range next {
+			next[i] = base
+		}
+
+		for i, outlinks := range index.outlinks {
+			for _, to := range outlinks {
+				next[to] += opts.Damping * scores[i] / float64(len(outlinks))
+			}
+		}
+
+		change := 0.0
+
+		for i := range next {
+			change += math.Abs(next[i] - scores[i])
+		}
+
+		scores = next
+
+		if change < opts.Tolerance {
+			break
+		}
+	}
+
+	for i, url := range index.urls {
+		ranks[url] = scores[i]
+	}
+
+	return ranks
+}
+
+// normalize scales scores so the sum of their squares is 1
+func normalize(scores []float64) {
+	sum := 0.0
+
+	for _, score := range scores {
+		sum += score * score
+	}
+
+	if sum == 0 {
+		return
+	}
+
+	norm := math.Sqrt(sum)
+
+	for i := range scores {
+		scores[i] /= norm
+	}
+}
+
+// HITS computes the hub and authority scores of every page in the graph, keyed by URL.
+// Good hubs link to many good authorities, and good authorities are linked to by many good hubs.
+// Links to assets and rel=nofollow links are ignored
+func (g *Graph) HITS(opts RankOptions) (hubs map[string]float64, authorities map[string]float64) {
+	index := g.linkGraph(passesEquity)
+	n := len(index.urls)
+
+	hubScores := make([]float64, n)
+	authorityScores := make([]float64, n)
+
+	for i := range hubScores {
+		hubScores[i] = 1
+		authorityScores[i] = 1
+	}
+
+	for iteration := 0; iteration < opts.Iterations; iteration++ {
+		nextAuthorities := make([]float64, n)
+
+		for i, inlinks := range index.inlinks {
+			for _, from := range inlinks {
+				nextAuthorities[i] += hubScores[from]
+			}
+		}
+
+		normalize(nextAuthorities)
+
+		nextHubs := make([]float64, n)
+
+		for i, outlinks := range index.outlinks {
+			for _, to := range outlinks {
+				nextHubs[i] += nextAuthorities[to]
+			}
+		}
+
+		normalize(nextHubs)
+
+		change := 0.0
+
+		for i := range nextHubs {
+			change += math.Abs(nextHubs[i]-hubScores[i]) + math.Abs(nextAuthorities[i]-authorityScores[i])
+		}
+
+		hubScores, authorityScores = nextHubs, nextAuthorities
+
+		if change < opts.Tolerance {
+			break
+		}
+	}
+
+	hubs = make(map[string]float64, n)
+	authorities = make(map[string]float64, n)
+
+	for i, url := range index.urls {
+		hubs[url] = hubScores[i]
+		authorities[url] = authorityScores[i]
+	}
+
+	return hubs, authorities
+}
+
+// Score holds the link equity scores of a page
+type Score struct {
+	// URL is the URL of the page
+	URL string
+
+	// PageRank is the page's PageRank
+	PageRank float64
+
+	// Hub is the page's HITS hub score
+	Hub float64
+
+	// Authority is the page's HITS authority score
+	Authority float64
+
+	// InDegree is the number of distinct links to the page
+	InDegree int
+}
+
+// Scores computes the PageRank and HITS scores of every page in the
+// graph, sorted from the highest PageRank to the lowest
+func (g *Graph) Scores(opts RankOptions) []Score {
+	ranks := g.PageRank(opts)
+	hubs, authorities := g.HITS(opts)
+
+	scores := make([]Score, 0, len(ranks))
+
+	for url, rank := range ranks {
+		scores = append(scores, Score{
+			URL:       url,
+			PageRank:  rank,
+			Hub:       hubs[url],
+			Authority: authorities[url],
+			InDegree:  g.InDegree(url),
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].PageRank != scores[j].PageRank {
+			return scores[i].PageRank > scores[j].PageRank
+		}
+
+		return scores[i].URL < scores[j].URL
+	})
+
+	return scores
+}
+
+// WriteScores writes a report of page scores to a writer, one page per line
+func WriteScores(w io.Writer, scores []Score) error {
+	if _, err := fmt.Fprintf(w, "%-10v %-10v %-10v %-8v %v\n", "PageRank", "Hub", "Authority", "Inlinks", "URL"); err != nil {
+		return err
+	}
+
+	for _, score := range scores {
+		_, err := fmt.Fprintf(w, "%-10.6f %-10.6f %-10.6f %-8v %v\n", score.PageRank, score.Hub, score.Authority, score.InDegree, score.URL)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
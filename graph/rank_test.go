@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"bytes"
+	"github.com/darthchudi/crwl/page"
+	"math"
+	"strings"
+	"testing"
+)
+
+// newRankGraph returns a graph where every page links to the home page,
+// the home page links to the about and blog pages, and the blog page
+// links to its post with a nofollow link and loads an image
+func newRankGraph(t *testing.T) *Graph {
+	g := NewGraph()
+
+	urls := []string{
+		"https://example.com",
+		"https://example.com/about",
+		"https://example.com/blog",
+		"https://example.com/blog/post",
+		"https://example.com/logo.png",
+	}
+
+	for _, url := range urls {
+		g.AddNode(url)
+	}
+
+	links := []struct {
+		from string
+		link page.Link
+	}{
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/about"}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/blog"}},
+		{from: "https://example.com/about", link: page.Link{URL: "https://example.com"}},
+		{from: "https://example.com/blog", link: page.Link{URL: "https://example.com"}},
+		{from: "https://example.com/blog", link: page.Link{URL: "https://example.com/blog/post", Rel: []string{"nofollow"}}},
+		{from: "https://example.com/blog", link: page.Link{URL: "https://example.com/logo.png", Kind: page.KindAsset}},
+		{from: "https://example.com/blog/post", link: page.Link{URL: "https://example.com"}},
+	}
+
+	for _, l := range links {
+		if err := g.AddLink(l.from, l.link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+func TestPageRank(t *testing.T) {
+	ranks := newRankGraph(t).PageRank(DefaultRankOptions())
+
+	// Assets are not ranked
+	if len(ranks) != 4 {
+		t.Fatalf("expected 4 ranked pages, got %v", len(ranks))
+	}
+
+	total := 0.0
+
+	for _, rank := range ranks {
+		total += rank
+	}
+
+	if math.Abs(total-1) > 1e-6 {
+		t.Fatalf("expected ranks to add up to 1, got %v", total)
+	}
+
+	home, about, blog, post := ranks["https://example.com"], ranks["https://example.com/about"], ranks["https://example.com/blog"], ranks["https://example.com/blog/post"]
+
+	if home <= about || math.Abs(about-blog) > 1e-6 {
+		t.Fatalf("expected the home page to outrank the equally ranked about and blog pages, got %v, %v and %v", home, about, blog)
+	}
+
+	// The post is only linked to with a nofollow link
+	if post >= about || math.Abs(post-0.15/4) > 1e-6 {
+		t.Fatalf("expected the post to only have the base rank, got %v", post)
+	}
+}
+
+func TestHITS(t *testing.T) {
+	hubs, authorities := newRankGraph(t).HITS(DefaultRankOptions())
+
+	// The home page is linked to by every other page
+	if authorities["https://example.com"] <= authorities["https://example.com/about"] {
+		t.Fatalf("expected the home page to be the best authority, got %v", authorities)
+	}
+
+	// The home page only links to pages that are poor authorities
+	if hubs["https://example.com/about"] <= hubs["https://example.com"] {
+		t.Fatalf("expected the about page to be a better hub than the home page, got %v", hubs)
+	}
+}
+
+func TestScores(t *testing.T) {
+	g := newRankGraph(t)
+	scores := g.Scores(DefaultRankOptions())
+
+	if len(scores) != 4 || scores[0].URL != "https://example.com" || scores[0].InDegree != 3 {
+		t.Fatalf("expected the home page to have the highest score, got %+v", scores)
+	}
+
+	for i := 1; i < len(scores); i++ {
+		if scores[i].PageRank > scores[i-1].PageRank {
+			t.Fatalf("expected scores to be sorted by pagerank, got %+v", scores)
+		}
+	}
+
+	var b bytes.Buffer
+
+	if err := WriteScores(&b, scores); err != nil {
+		t.Fatalf("write scores error: %v", err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); len(lines) != 5 {
+		t.Fatalf("expected a header and 4 score lines, got %v", lines)
+	}
+}
@@ -0,0 +1,332 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"io"
+	"time"
+)
+
+// magic is written at the start of graphs saved in the binary format
+const magic = "CRWLGRPH"
+
+// formatVersion is the version of the formats graphs are saved in.
+// It is increased whenever a format changes in a way older versions can't read
+const formatVersion = 1
+
+// maxStringLength is the length of the longest string a saved graph can hold
+const maxStringLength = 1 << 24
+
+var (
+	// ErrInvalidGraph is returned when loading a graph from data that isn't a saved graph
+	ErrInvalidGraph = errors.New("invalid saved graph")
+
+	// ErrUnsupportedVersion is returned when loading a graph saved in a version of a format that can't be read
+	ErrUnsupportedVersion = errors.New("unsupported saved graph version")
+)
+
+// Save writes the graph to a writer in a compact binary format, including its seed,
+// every node's metadata and every edge. URLs are only written once, with edges referring to nodes by position.
+// Graphs are read back with Load
+func (g *Graph) Save(w io.Writer) error {
+	snapshot := g.Snapshot()
+	nodes := snapshot.Nodes()
+	positions := make(map[string]uint64, len(nodes))
+
+	b := &binaryWriter{w: bufio.NewWriter(w)}
+
+	b.bytes([]byte(magic))
+	b.uvarint(formatVersion)
+	b.string(snapshot.Seed)
+
+	b.bool(snapshot.Normalization.SortQuery)
+	b.strings(snapshot.Normalization.StripQuery)
+	b.bool(snapshot.Normalization.KeepTrailingSlash)
+
+	b.uvarint(uint64(len(nodes)))
+
+	for i, node := range nodes {
+		positions[node.url] = uint64(i)
+
+		b.string(node.url)
+		b.string(string(node.status))
+		b.string(node.canonical)
+		b.varint(int64(node.depth))
+		b.string(node.title)
+		b.varint(int64(node.statusCode))
+		b.string(node.contentType)
+		b.varint(node.size)
+		b.varint(int64(node.latency))
+		b.string(node.discoveredFrom)
+		b.time(node.discoveredAt)
+		b.time(node.fetchedAt)
+		b.bool(node.noIndex)
+	}
+
+	edges := snapshot.Edges()
+	b.uvarint(uint64(len(edges)))
+
+	for _, edge := range edges {
+		b.uvarint(positions[edge.From])
+		b.uvarint(positions[edge.To])
+		b.string(string(edge.Kind))
+		b.uvarint(uint64(edge.Weight))
+		b.uvarint(uint64(edge.NoFollow))
+		b.strings(edge.Texts)
+
+		b.string(edge.Link.URL)
+		b.string(edge.Link.Text)
+		b.string(edge.Link.Title)
+		b.strings(edge.Link.Rel)
+		b.string(edge.Link.Hreflang)
+		b.string(edge.Link.Element)
+		b.string(edge.Link.Attribute)
+		b.string(string(edge.Link.Kind))
+		b.varint(int64(edge.Link.Position))
+	}
+
+	return b.flush()
+}
+
+// Load reads a graph written by Save or SaveJSON from a reader
+func Load(r io.Reader) (*Graph, error) {
+	reader := bufio.NewReader(r)
+	header, err := reader.Peek(len(magic))
+
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if string(header) == magic {
+		return loadBinary(reader)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(header), []byte("{")) {
+		return loadJSON(reader)
+	}
+
+	return nil, fmt.Errorf("%w: unknown format", ErrInvalidGraph)
+}
+
+// loadBinary reads a graph in the binary format written by Save
+func loadBinary(r *bufio.Reader) (*Graph, error) {
+	b := &binaryReader{r: r}
+
+	b.bytes(len(magic))
+
+	version := b.uvarint()
+
+	if b.err == nil && version != formatVersion {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, version)
+	}
+
+	g := NewGraph()
+	g.Seed = b.string()
+	g.Normalization.SortQuery = b.bool()
+	g.Normalization.StripQuery = b.strings()
+	g.Normalization.KeepTrailingSlash = b.bool()
+
+	nodeCount := b.uvarint()
+	ids := []uint32{}
+
+	for i := uint64(0); i < nodeCount && b.err == nil; i++ {
+		ids = append(ids, g.addNode(Node{
+			url:            b.string(),
+			status:         Status(b.string()),
+			canonical:      b.string(),
+			depth:          int(b.varint()),
+			title:          b.string(),
+			statusCode:     int(b.varint()),
+			contentType:    b.string(),
+			size:           b.varint(),
+			latency:        time.Duration(b.varint()),
+			discoveredFrom: b.string(),
+			discoveredAt:   b.time(),
+			fetchedAt:      b.time(),
+			noIndex:        b.bool(),
+		}))
+	}
+
+	edgeCount := b.uvarint()
+
+	for i := uint64(0); i < edgeCount && b.err == nil; i++ {
+		from, to := b.uvarint(), b.uvarint()
+
+		kind, weight, noFollow := Kind(b.string()), int(b.uvarint()), int(b.uvarint())
+		texts := b.strings()
+
+		link := page.Link{
+			URL:       b.string(),
+			Text:      b.string(),
+			Title:     b.string(),
+			Rel:       b.strings(),
+			Hreflang:  b.string(),
+			Element:   b.string(),
+			Attribute: b.string(),
+			Kind:      page.Kind(b.string()),
+			Position:  int(b.varint()),
+		}
+
+		if b.err != nil {
+			break
+		}
+
+		if from >= uint64(len(ids)) || to >= uint64(len(ids)) {
+			return nil, fmt.Errorf("%w: edge %v refers to a missing node", ErrInvalidGraph, i)
+		}
+
+		g.insertEdge(ids[from], ids[to], kind, weight, noFollow, texts, link)
+	}
+
+	if b.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGraph, b.err)
+	}
+
+	return g, nil
+}
+
+// binaryWriter writes values in the binary format, keeping the first error that occurs
+type binaryWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (b *binaryWriter) bytes(value []byte) {
+	if b.err == nil {
+		_, b.err = b.w.Write(value)
+	}
+}
+
+func (b *binaryWriter) uvarint(value uint64) {
+	b.bytes(b.buf[:binary.PutUvarint(b.buf[:], value)])
+}
+
+func (b *binaryWriter) varint(value int64) {
+	b.bytes(b.buf[:binary.PutVarint(b.buf[:], value)])
+}
+
+func (b *binaryWriter) bool(value bool) {
+	if value {
+		b.uvarint(1)
+	} else {
+		b.uvarint(0)
+	}
+}
+
+func (b *binaryWriter) string(value string) {
+	b.uvarint(uint64(len(value)))
+	b.bytes([]byte(value))
+}
+
+func (b *binaryWriter) strings(values []string) {
+	b.uvarint(uint64(len(values)))
+
+	for _, value := range values {
+		b.string(value)
+	}
+}
+
+// time writes a time as nanoseconds since the Unix epoch, or 0 for the zero time
+func (b *binaryWriter) time(value time.Time) {
+	if value.IsZero() {
+		b.varint(0)
+		return
+	}
+
+	b.varint(value.UnixNano())
+}
+
+// flush writes any buffered data to the underlying writer
+func (b *binaryWriter) flush() error {
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.w.Flush()
+}
+
+// binaryReader reads values in the binary format, keeping the first error that occurs.
+// Once an error has occurred, every value read is a zero value
+type binaryReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (b *binaryReader) bytes(n int) []byte {
+	if b.err != nil {
+		return nil
+	}
+
+	value := make([]byte, n)
+
+	if _, err := io.ReadFull(b.r, value); err != nil {
+		b.err = err
+		return nil
+	}
+
+	return value
+}
+
+func (b *binaryReader) uvarint() uint64 {
+	if b.err != nil {
+		return 0
+	}
+
+	value, err := binary.ReadUvarint(b.r)
+	b.err = err
+
+	return value
+}
+
+func (b *binaryReader) varint() int64 {
+	if b.err != nil {
+		return 0
+	}
+
+	value, err := binary.ReadVarint(b.r)
+	b.err = err
+
+	return value
+}
+
+func (b *binaryReader) bool() bool {
+	return b.uvarint() == 1
+}
+
+func (b *binaryReader) string() string {
+	length := b.uvarint()
+
+	if length > maxStringLength {
+		b.err = fmt.Errorf("string of %v bytes is too long", length)
+		return ""
+	}
+
+	return string(b.bytes(int(length)))
+}
+
+func (b *binaryReader) strings() []string {
+	count := b.uvarint()
+	values := []string{}
+
+	for i := uint64(0); i < count && b.err == nil; i++ {
+		values = append(values, b.string())
+	}
+
+	return values
+}
+
+// time reads a time written as nanoseconds since the Unix epoch
+func (b *binaryReader) time() time.Time {
+	nanoseconds := b.varint()
+
+	if nanoseconds == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanoseconds)
+}
@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/darthchudi/crwl/page"
+	"github.com/darthchudi/crwl/urlnorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSavedGraph returns a graph with node metadata and edges of several kinds to save
+func newSavedGraph(t *testing.T) *Graph {
+	g := NewGraph()
+	g.Seed = "https://example.com"
+	g.Normalization = urlnorm.Options{SortQuery: true, StripQuery: []string{"utm_*"}}
+
+	for _, url := range []string{"https://example.com", "https://example.com/about", "https://example.com/logo.png", "https://example.com/old"} {
+		g.AddNode(url)
+	}
+
+	g.SetStatus("https://example.com", StatusFetched)
+	g.SetTitle("https://example.com", "Home")
+	g.SetNoIndex("https://example.com/about", true)
+	g.SetCanonical("https://example.com/old", "https://example.com/about")
+	g.SetDepth("https://example.com/about", 1)
+	g.SetDiscoveredFrom("https://example.com/about", "https://example.com")
+	g.SetFetch("https://example.com", Fetch{
+		StatusCode:  200,
+		ContentType: "text/html",
+		Size:        1024,
+		Latency:     123456789,
+		FetchedAt:   time.Unix(1700000000, 42),
+	})
+
+	links := []struct {
+		from string
+		link page.Link
+	}{
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/about", Text: "About", Title: "About us", Rel: []string{"nofollow"}, Element: "a", Attribute: "href"}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/about", Text: "Our story", Element: "a", Attribute: "href", Position: 1}},
+		{from: "https://example.com", link: page.Link{URL: "https://example.com/logo.png", Element: "img", Attribute: "src", Kind: page.KindAsset, Position: 2}},
+		{from: "https://example.com/old", link: page.Link{URL: "https://example.com/about", Kind: page.KindRedirect}},
+	}
+
+	for _, l := range links {
+		if err := g.AddLink(l.from, l.link); err != nil {
+			t.Fatalf("add link error: %v", err)
+		}
+	}
+
+	return g
+}
+
+// describeGraph describes every node and edge of a graph, so graphs can be compared
+func describeGraph(g *Graph) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%v %+v\n", g.Seed, g.Normalization)
+
+	for _, node := range g.Nodes() {
+		fmt.Fprintf(&b, "%v %v %v %v %v %v %v %v %v %v %v %v %v\n", node.URL(), node.Status(), node.Canonical(), node.NoIndex(), node.Depth(), node.Title(),
+			node.StatusCode(), node.ContentType(), node.Size(), node.Latency(), node.DiscoveredFrom(), node.DiscoveredAt().UnixNano(), node.FetchedAt().UnixNano())
+	}
+
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(&b, "%+v\n", edge)
+	}
+
+	return b.String()
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	g := newSavedGraph(t)
+
+	tests := []struct {
+		name string
+		save func(g *Graph, b *bytes.Buffer) error
+	}{
+		{name: "binary", save: func(g *Graph, b *bytes.Buffer) error { return g.Save(b) }},
+		{name: "json", save: func(g *Graph, b *bytes.Buffer) error { return g.SaveJSON(b) }},
+	}
+
+	for _, tc := range tests {
+		var b bytes.Buffer
+
+		if err := tc.save(g, &b); err != nil {
+			t.Fatalf("%v: save error: %v", tc.name, err)
+		}
+
+		loaded, err := Load(&b)
+
+		if err != nil {
+			t.Fatalf("%v: load error: %v", tc.name, err)
+		}
+
+		if describeGraph(loaded) != describeGraph(g) {
+			t.Errorf("%v: expected loaded graph:\n%v\ngot:\n%v", tc.name, describeGraph(g), describeGraph(loaded))
+		}
+
+		// Loaded graphs index their edges, so links found again add to their weight
+		loaded.AddLink("https://example.com", page.Link{URL: "https://example.com/about", Text: "About"})
+
+		if edges := loaded.Outlinks("https://example.com"); edges[0].Weight != 3 || loaded.InDegree("https://example.com/about") != 2 {
+			t.Errorf("%v: expected the loaded edge to have a weight of 3, got %+v", tc.name, edges[0])
+		}
+	}
+}
+
+func TestSaveJSONLines(t *testing.T) {
+	var b bytes.Buffer
+
+	if err := newSavedGraph(t).SaveJSON(&b); err != nil {
+		t.Fatalf("save error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+
+	// A header, 4 nodes and 3 edges
+	if len(lines) != 8 || !strings.HasPrefix(lines[0], fmt.Sprintf(`{"format":"crwl-graph","version":%v,"seed":"https://example.com"`, formatVersion)) || !strings.HasPrefix(lines[1], `{"node":{"url":"https://example.com"`) {
+		t.Fatalf("unexpected json lines:\n%v", b.String())
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	var saved bytes.Buffer
+
+	if err := newSavedGraph(t).Save(&saved); err != nil {
+		t.Fatalf("save error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{name: "empty", data: "", err: ErrInvalidGraph},
+		{name: "unknown format", data: "digraph crawl {}", err: ErrInvalidGraph},
+		{name: "truncated binary", data: saved.String()[:saved.Len()/2], err: ErrInvalidGraph},
+		{name: "newer binary version", data: magic + string(rune(formatVersion+1)), err: ErrUnsupportedVersion},
+		{name: "older binary version", data: magic + string(rune(formatVersion-1)), err: ErrUnsupportedVersion},
+		{name: "newer json version", data: fmt.Sprintf(`{"format":"crwl-graph","version":%v}`, formatVersion+1), err: ErrUnsupportedVersion},
+		{name: "json edge to a missing node", data: `{"format":"crwl-graph","version":1}` + "\n" + `{"edge":{"from":"https://example.com","to":"https://example.com/about"}}`, err: ErrInvalidGraph},
+	}
+
+	for _, tc := range tests {
+		if _, err := Load(strings.NewReader(tc.data)); !errors.Is(err, tc.err) {
+			t.Errorf("%v: expected error %v, got %v", tc.name, tc.err, err)
+		}
+	}
+}
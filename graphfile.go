@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/darthchudi/crwl/graph"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// saveGraph saves a graph to a file. Files with a .json, .jsonl or .ndjson extension
+// are saved as newline delimited JSON, and any other file in the binary format
+func saveGraph(path string, g *graph.Graph) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".jsonl", ".ndjson":
+		err = g.SaveJSON(file)
+	default:
+		err = g.Save(file)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// loadGraph loads a graph saved in either format from a file
+func loadGraph(path string) (*graph.Graph, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return graph.Load(file)
+}
@@ -1,22 +1,314 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/darthchudi/crwl/crawler"
+	"github.com/darthchudi/crwl/fetcher"
+	"github.com/darthchudi/crwl/progress"
+	"github.com/darthchudi/crwl/ratelimit"
+	"github.com/darthchudi/crwl/stats"
+	"github.com/darthchudi/crwl/urlnorm"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
-func main() {
-	crawlURL := flag.String("url", "https://example.com", "URL to Crawl")
-	workers := flag.Int("workers", 20, "Workers defines the maximum number of concurrent connections to the provided domain")
-	requestTimeout := flag.Duration("timeout", 30*time.Second, "How long should a request to fetch a page take")
+// splitList splits a comma separated flag value into its items
+func splitList(value string) []string {
+	items := []string{}
 
-	flag.Parse()
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// crawlFlags are the flags that configure a crawl. They are shared by every command
+type crawlFlags struct {
+	crawlURL          *string
+	workers           *int
+	requestTimeout    *time.Duration
+	maxDepth          *int
+	maxPages          *int64
+	maxBytes          *int64
+	maxDuration       *time.Duration
+	userAgent         *string
+	ignoreRobots      *bool
+	rate              *float64
+	burst             *int
+	retries           *int
+	retryDelay        *time.Duration
+	sortQuery         *bool
+	stripQuery        *string
+	keepTrailingSlash *bool
+	retryOn           *string
+	ignoreAssets      *bool
+	externalAssets    *bool
+	deepThreshold     *int
+	urls              *string
+	save              *string
+	load              *string
+	metrics           *string
+	maxBodySize       *int64
+	progress          *bool
+	statsFile         *string
+	statsInterval     *time.Duration
+}
+
+// newCrawlFlags defines the crawl flags on a flag set
+func newCrawlFlags(fs *flag.FlagSet) *crawlFlags {
+	return &crawlFlags{
+		crawlURL:          fs.String("url", "https://example.com", "URL to Crawl"),
+		workers:           fs.Int("workers", 20, "Workers defines the maximum number of concurrent connections to the provided domain"),
+		requestTimeout:    fs.Duration("timeout", 30*time.Second, "How long should a request to fetch a page take"),
+		maxDepth:          fs.Int("max-depth", 0, "Maximum number of clicks from the starting URL to follow (0 means no limit)"),
+		maxPages:          fs.Int64("max-pages", 0, "Maximum number of pages to fetch (0 means no limit)"),
+		maxBytes:          fs.Int64("max-bytes", 0, "Maximum number of bytes to download (0 means no limit)"),
+		maxDuration:       fs.Duration("max-duration", 0, "Maximum amount of time to crawl for (0 means no limit)"),
+		userAgent:         fs.String("user-agent", fetcher.DefaultUserAgent, "User-agent sent with requests and used to match robots.txt rules"),
+		ignoreRobots:      fs.Bool("ignore-robots", false, "Fetch URLs even if robots.txt disallows them"),
+		rate:              fs.Float64("rate", 0, "Maximum number of requests per second to each host (0 means no limit)"),
+		burst:             fs.Int("burst", 1, "Number of requests that can be made to a host at once when rate limited"),
+		retries:           fs.Int("retries", 3, "Maximum number of times to fetch a URL, including the first attempt"),
+		retryDelay:        fs.Duration("retry-delay", 500*time.Millisecond, "Delay before the first retry of a failed fetch, doubled for every retry after that"),
+		sortQuery:         fs.Bool("sort-query", false, "Sort the query parameters of URLs so differently ordered URLs are crawled once"),
+		stripQuery:        fs.String("strip-query", "", "Comma separated list of query parameters to remove from URLs e.g utm_*,fbclid"),
+		keepTrailingSlash: fs.Bool("keep-trailing-slash", false, "Keep trailing slashes in URL paths instead of removing them"),
+		retryOn:           fs.String("retry-on", "timeouts,resets,5xx,429", "Comma separated list of failures to retry"),
+		ignoreAssets:      fs.Bool("ignore-assets", false, "Don't check the images, scripts, stylesheets and other assets pages load"),
+		externalAssets:    fs.Bool("external-assets", false, "Check assets served from other domains"),
+		deepThreshold:     fs.Int("deep-threshold", 3, "Number of clicks from the starting URL beyond which pages are reported as deep (0 means pages aren't reported)"),
+		urls:              fs.String("urls", "", "Sitemap or file with one URL per line of pages known to exist, reported as orphans if links don't lead to them"),
+		save:              fs.String("save", "", "File to save the crawl graph to, as JSON lines if it ends in .json, .jsonl or .ndjson and in a binary format otherwise"),
+		load:              fs.String("load", "", "File to load a saved crawl graph from instead of crawling"),
+		maxBodySize:       fs.Int64("max-body-size", 0, "Maximum size in bytes of a response body to download, larger responses fail (0 means no limit)"),
+		progress:          fs.Bool("progress", true, "Show the crawl's progress, refreshed in place on a terminal and as a line every 10s otherwise"),
+		metrics:           fs.String("metrics", "", "Address to serve Prometheus metrics on at /metrics while crawling e.g :9090"),
+		statsFile:         fs.String("stats-file", "", "File to save the crawl's stats to as JSON, with a time series of snapshots taken every --stats-interval"),
+		statsInterval:     fs.Duration("stats-interval", time.Second, "How often to take a snapshot of the crawl's stats for --stats-file"),
+	}
+}
+
+// crawler creates a crawler configured by the flags
+func (f *crawlFlags) crawler() *crawler.Crawler {
+	retryFailures, err := fetcher.ParseRetryOn(*f.retryOn)
+
+	if err != nil {
+		log.Fatalf("🥞 Invalid --retry-on flag: %v", err)
+	}
+
+	c := crawler.NewCrawler(*f.crawlURL, *f.workers, *f.requestTimeout)
+	c.Limits = crawler.Limits{
+		MaxDepth:    *f.maxDepth,
+		MaxPages:    *f.maxPages,
+		MaxBytes:    *f.maxBytes,
+		MaxDuration: *f.maxDuration,
+	}
+	c.UserAgent = *f.userAgent
+	c.IgnoreRobots = *f.ignoreRobots
+	c.IgnoreAssets = *f.ignoreAssets
+	c.CheckExternalAssets = *f.externalAssets
+	c.RateLimiter = ratelimit.NewLimiter(*f.rate, *f.burst)
+	c.Normalization = urlnorm.Options{
+		SortQuery:         *f.sortQuery,
+		StripQuery:        splitList(*f.stripQuery),
+		KeepTrailingSlash: *f.keepTrailingSlash,
+	}
+	c.RetryPolicy.MaxAttempts = *f.retries
+	c.RetryPolicy.BaseDelay = *f.retryDelay
+	c.RetryPolicy.RetryOn = retryFailures
+
+	if httpFetcher, ok := c.Fetcher.(*fetcher.HTTPFetcher); ok {
+		httpFetcher.UserAgent = *f.userAgent
+		httpFetcher.MaxBodySize = *f.maxBodySize
+	}
+
+	return c
+}
+
+// knownURLs returns the URLs of pages known to exist from the --urls flag
+func (f *crawlFlags) knownURLs() []string {
+	if *f.urls == "" {
+		return []string{}
+	}
+
+	file, err := os.Open(*f.urls)
+
+	if err != nil {
+		log.Fatalf("🥞 Failed to open --urls file: %v", err)
+	}
+
+	defer file.Close()
+
+	urls, err := readURLs(file)
+
+	if err != nil {
+		log.Fatalf("🥞 Failed to read --urls file: %v", err)
+	}
+
+	return urls
+}
+
+// crawl runs a crawl until it completes or the process receives SIGINT/SIGTERM,
+// and prints its stats, failed URLs, click-depth report and structure report to a writer.
+// If a saved graph is loaded with --load, its reports are printed from its seed without crawling
+func (f *crawlFlags) crawl(c *crawler.Crawler, w io.Writer) {
+	urls := f.knownURLs()
+
+	if *f.load != "" {
+		g, err := loadGraph(*f.load)
+
+		if err != nil {
+			log.Fatalf("🥞 Failed to load graph: %v", err)
+		}
+
+		c.Graph = g
+
+		// The graph's depths were found from its seed, so its reports start there too
+		if g.Seed != "" {
+			c.URL = g.Seed
+		}
+
+		f.report(c, urls, w)
+		fmt.Fprintf(w, "Loaded %v URLs from %v", g.NodeCount(), *f.load)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Stop crawling gracefully on SIGINT/SIGTERM. A second signal kills the process.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
-	c := crawler.NewCrawler(*crawlURL, *workers, *requestTimeout)
-	c.Crawl()
+	go func() {
+		sig := <-signals
+		log.Printf("🛑 Received %v, stopping crawl", sig)
+
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	if *f.metrics != "" {
+		stop := serveMetrics(*f.metrics, c.Stats)
+		defer stop()
+	}
+
+	var recorder *stats.Recorder
+
+	if *f.statsFile != "" {
+		if *f.statsInterval <= 0 {
+			log.Fatalf("🥞 Invalid --stats-interval flag: %v must be positive", *f.statsInterval)
+		}
+
+		recorder = stats.NewRecorder(c.Stats, *f.statsInterval)
+		recorder.Start()
+	}
+
+	stopProgress := f.showProgress(c)
+	err := c.Crawl(ctx)
+	stopProgress()
+
+	// The recorder is stopped after the crawl, so its final snapshot has the crawl's duration
+	if recorder != nil {
+		recorder.Stop()
+	}
+
+	if err != nil {
+		log.Printf("🛑 Crawl stopped early: %v", err)
+	}
 
 	c.Stats.Print()
-	fmt.Printf("Finished crawling %v URLs in in %v", c.Stats.Total(), c.Stats.Duration())
+	c.Stats.FailureReport().Write(w)
+	f.report(c, urls, w)
+
+	if *f.save != "" {
+		if err := saveGraph(*f.save, c.Graph); err != nil {
+			log.Fatalf("🥞 Failed to save graph: %v", err)
+		}
+
+		log.Printf("💾 Saved graph to %v", *f.save)
+	}
+
+	if recorder != nil {
+		if err := saveStats(*f.statsFile, recorder); err != nil {
+			log.Fatalf("🥞 Failed to save stats: %v", err)
+		}
+
+		log.Printf("💾 Saved stats to %v", *f.statsFile)
+	}
+
+	fmt.Fprintf(w, "Finished crawling %v URLs in in %v", c.Stats.Total(), c.Stats.Duration())
+}
+
+// showProgress shows a crawler's progress on the standard logger's writer while it crawls, if
+// the --progress flag is set, so the reports written to stdout aren't interleaved with it. On a
+// terminal the logs are written above a status line that is refreshed in place. It returns a
+// function that stops showing the progress
+func (f *crawlFlags) showProgress(c *crawler.Crawler) (stop func()) {
+	if !*f.progress {
+		return func() {}
+	}
+
+	logOutput := log.Writer()
+	logWriter := c.LogWriter
+
+	live := progress.IsTerminal(logOutput)
+	display := progress.NewDisplay(c.Stats, logOutput, live)
+
+	if live {
+		log.SetOutput(display)
+
+		// The crawler's logs only go above the status line if they are written to a terminal too
+		if progress.IsTerminal(c.LogWriter) {
+			c.LogWriter = display
+		}
+	}
+
+	display.Start()
+
+	return func() {
+		display.Stop()
+		log.SetOutput(logOutput)
+		c.LogWriter = logWriter
+	}
+}
+
+// report prints the click-depth and structure reports of a crawler's graph to a writer
+func (f *crawlFlags) report(c *crawler.Crawler, urls []string, w io.Writer) {
+	c.Graph.DepthReport(c.URL, *f.deepThreshold).Write(w)
+	c.Graph.StructureReport(c.URL, urls).Write(w)
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "rank":
+			runRank(os.Args[2:])
+			return
+		case "path":
+			runPath(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		}
+	}
+
+	flags := newCrawlFlags(flag.CommandLine)
+	flag.Parse()
+
+	flags.crawl(flags.crawler(), os.Stdout)
 }
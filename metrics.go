@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"github.com/darthchudi/crwl/stats"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// serveMetrics serves a crawler's stats in the Prometheus text format at /metrics on an
+// address, so a crawl can be watched while it runs. It returns a function that stops the server
+func serveMetrics(addr string, s *stats.Stats) (stop func()) {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		log.Fatalf("🥞 Failed to serve metrics: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.Handler())
+
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("🥞 Metrics server stopped: %v", err)
+		}
+	}()
+
+	log.Printf("📈 Serving metrics at http://%v/metrics", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		server.Shutdown(ctx)
+	}
+}
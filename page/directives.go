@@ -0,0 +1,123 @@
+package page
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/darthchudi/crwl/robots"
+	"github.com/darthchudi/crwl/urlnorm"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// valueDirectives are X-Robots-Tag directives that take a value after a colon,
+// which shouldn't be mistaken for a user-agent e.g "unavailable_after: 25 Jun 2010"
+var valueDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// applyRobotsDirectives applies a comma separated list of robots directives
+// e.g "noindex, nofollow" to the page
+func (p *Page) applyRobotsDirectives(directives string) {
+	for _, directive := range strings.Split(directives, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			p.NoIndex = true
+		case "nofollow":
+			p.NoFollow = true
+		case "none":
+			p.NoIndex = true
+			p.NoFollow = true
+		}
+	}
+}
+
+// appliesToCrawler checks if directives meant for a user-agent apply to the crawler.
+// Directives for the "robots" user-agent apply to every crawler
+func (p *Page) appliesToCrawler(userAgent string) bool {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+
+	return userAgent == "robots" || (p.options.UserAgent != "" && userAgent == robots.ProductToken(p.options.UserAgent))
+}
+
+// applyRobotsHeader applies the directives of X-Robots-Tag headers to the page.
+// Directives scoped to another user-agent e.g "googlebot: noindex" are ignored
+func (p *Page) applyRobotsHeader(header http.Header) {
+	for _, value := range header.Values("X-Robots-Tag") {
+		if i := strings.Index(value, ":"); i >= 0 {
+			prefix := strings.ToLower(strings.TrimSpace(value[:i]))
+
+			if !strings.ContainsAny(prefix, " ,") && !valueDirectives[prefix] {
+				if !p.appliesToCrawler(prefix) {
+					continue
+				}
+
+				value = value[i+1:]
+			}
+		}
+
+		p.applyRobotsDirectives(value)
+	}
+}
+
+// readDirectives reads the page-level directives in the page's HTML document:
+// its <base href>, its rel=canonical link and its robots <meta> tags
+func (p *Page) readDirectives() {
+	if href, exists := p.Document.Find("base[href]").First().Attr("href"); exists {
+		// Keep the trailing slash so relative links resolve inside the base directory
+		base, err := urlnorm.Resolve(p.BaseURL, href, urlnorm.Options{KeepTrailingSlash: true})
+
+		if err != nil {
+			log.Printf("🤠 %v Base URL error \n", err)
+		} else {
+			p.BaseURL = base
+		}
+	}
+
+	p.Document.Find("link[rel][href]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if !hasRel(s, "canonical") {
+			return true
+		}
+
+		href, _ := s.Attr("href")
+		canonical, err := p.normalizeURL(href)
+
+		if err != nil {
+			log.Printf("🤠 %v Canonical URL error \n", err)
+			return true
+		}
+
+		p.CanonicalURL = canonical
+		return false
+	})
+
+	p.Document.Find("meta[name][content]").Each(func(i int, s *goquery.Selection) {
+		name, _ := s.Attr("name")
+
+		if !p.appliesToCrawler(name) {
+			return
+		}
+
+		content, _ := s.Attr("content")
+		p.applyRobotsDirectives(content)
+	})
+}
+
+// hasRel checks if an element's rel attribute contains a value e.g "nofollow"
+func hasRel(s *goquery.Selection, value string) bool {
+	rel, exists := s.Attr("rel")
+
+	if !exists {
+		return false
+	}
+
+	for _, field := range strings.Fields(rel) {
+		if strings.EqualFold(field, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,253 @@
+package page
+
+import (
+	"errors"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/darthchudi/crwl/urlnorm"
+	"golang.org/x/net/html"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind describes what a URL discovered on a page points to
+type Kind string
+
+const (
+	// KindPage is a navigable page that the crawler follows e.g the target of an anchor
+	KindPage Kind = "page"
+
+	// KindAsset is a resource the page loads e.g an image, script or stylesheet.
+	// Assets are checked by the crawler but not parsed for links
+	KindAsset Kind = "asset"
+
+	// KindForm is the target of a form submission. Form targets are recorded
+	// but never requested, since submitting a form can have side effects
+	KindForm Kind = "form"
+
+	// KindRedirect is a URL that a page's URL redirects to.
+	// Redirects are recorded from a page's response, not its HTML
+	KindRedirect Kind = "redirect"
+
+	// KindCanonical is the URL a page declares as its canonical URL.
+	// Canonical URLs are recorded from a page's rel=canonical link, not its rules
+	KindCanonical Kind = "canonical"
+)
+
+// Format describes how URLs are written in an attribute value
+type Format int
+
+const (
+	// FormatURL is an attribute holding a single URL e.g `href`
+	FormatURL Format = iota
+
+	// FormatSrcset is a `srcset` attribute holding a comma separated
+	// list of URLs with their width or pixel density
+	FormatSrcset
+
+	// FormatCSS is CSS holding URLs in `url()` references and `@import` rules
+	FormatCSS
+)
+
+// Rule describes where URLs are found in a page's HTML document
+type Rule struct {
+	// Selector is the CSS selector of the elements holding URLs e.g `img[src]`
+	Selector string
+
+	// Attribute is the attribute holding the URLs. If it is empty, the
+	// element's text is used e.g for the contents of a <style> element
+	Attribute string
+
+	// Format describes how URLs are written in the attribute
+	Format Format
+
+	// Kind is the kind of the URLs found with the rule
+	Kind Kind
+}
+
+// DefaultRules are the rules links are extracted with when a page's options don't set any
+var DefaultRules = []Rule{
+	{Selector: "a[href]", Attribute: "href", Kind: KindPage},
+	{Selector: "area[href]", Attribute: "href", Kind: KindPage},
+	{Selector: "iframe[src]", Attribute: "src", Kind: KindPage},
+	{Selector: "frame[src]", Attribute: "src", Kind: KindPage},
+	{Selector: "link[rel~=alternate][href]", Attribute: "href", Kind: KindPage},
+	{Selector: "link[rel~=next][href]", Attribute: "href", Kind: KindPage},
+	{Selector: "link[rel~=prev][href]", Attribute: "href", Kind: KindPage},
+	{Selector: "link[rel~=stylesheet][href]", Attribute: "href", Kind: KindAsset},
+	{Selector: "link[rel~=icon][href]", Attribute: "href", Kind: KindAsset},
+	{Selector: "link[rel~=preload][href]", Attribute: "href", Kind: KindAsset},
+	{Selector: "link[rel~=manifest][href]", Attribute: "href", Kind: KindAsset},
+	{Selector: "img[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "img[srcset]", Attribute: "srcset", Format: FormatSrcset, Kind: KindAsset},
+	{Selector: "source[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "source[srcset]", Attribute: "srcset", Format: FormatSrcset, Kind: KindAsset},
+	{Selector: "script[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "video[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "video[poster]", Attribute: "poster", Kind: KindAsset},
+	{Selector: "audio[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "track[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "embed[src]", Attribute: "src", Kind: KindAsset},
+	{Selector: "object[data]", Attribute: "data", Kind: KindAsset},
+	{Selector: "[style]", Attribute: "style", Format: FormatCSS, Kind: KindAsset},
+	{Selector: "style", Format: FormatCSS, Kind: KindAsset},
+	{Selector: "form[action]", Attribute: "action", Kind: KindForm},
+}
+
+// cssURLPattern matches URLs in CSS `url()` references and `@import` rules
+var cssURLPattern = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// parseCSSURLs returns the URLs referenced in CSS
+func parseCSSURLs(css string) []string {
+	urls := []string{}
+
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		for _, url := range match[1:] {
+			if url != "" {
+				urls = append(urls, url)
+				break
+			}
+		}
+	}
+
+	return urls
+}
+
+// parseSrcset returns the URLs in a srcset attribute e.g
+// `small.jpg 480w, large.jpg 1080w`
+func parseSrcset(srcset string) []string {
+	urls := []string{}
+
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		urls = append(urls, fields[0])
+	}
+
+	return urls
+}
+
+// extractURLs returns the raw URLs a rule finds in an element
+func extractURLs(s *goquery.Selection, rule Rule) []string {
+	value := s.Text()
+
+	if rule.Attribute != "" {
+		value = s.AttrOr(rule.Attribute, "")
+	}
+
+	switch rule.Format {
+	case FormatSrcset:
+		return parseSrcset(value)
+	case FormatCSS:
+		return parseCSSURLs(value)
+	default:
+		return []string{value}
+	}
+}
+
+// linkText returns the anchor text of an element with its whitespace collapsed.
+// The alt text of the element, or of an image inside it, is used if it has no text
+func linkText(s *goquery.Selection) string {
+	text := strings.Join(strings.Fields(s.Text()), " ")
+
+	if text != "" {
+		return text
+	}
+
+	if alt, exists := s.Attr("alt"); exists {
+		return strings.TrimSpace(alt)
+	}
+
+	return strings.TrimSpace(s.Find("img[alt]").First().AttrOr("alt", ""))
+}
+
+// extractLinks finds every link in the page according to the page's rules.
+// Links are returned in document order, and every occurrence of a URL is recorded
+func (p *Page) extractLinks() []Link {
+	rules := p.options.Rules
+
+	if rules == nil {
+		rules = DefaultRules
+	}
+
+	// order holds the position of each element in the document
+	order := make(map[*html.Node]int)
+
+	p.Document.Find("*").Each(func(i int, s *goquery.Selection) {
+		order[s.Get(0)] = i
+	})
+
+	links := []Link{}
+
+	// elements holds the position of the element each link was found in
+	elements := []int{}
+
+	for _, rule := range rules {
+		p.Document.Find(rule.Selector).Each(func(i int, s *goquery.Selection) {
+			for _, rawURL := range extractURLs(s, rule) {
+				rawURL = strings.TrimSpace(rawURL)
+
+				// Links to a fragment of the page itself don't point to another resource
+				if rawURL == "" || strings.HasPrefix(rawURL, "#") {
+					continue
+				}
+
+				url, err := p.normalizeURL(rawURL)
+
+				// URLs such as `mailto:`, `javascript:` and `data:` URLs can't be crawled
+				if errors.Is(err, urlnorm.ErrUnsupportedScheme) {
+					continue
+				}
+
+				if err != nil {
+					log.Printf("🤠 %v URL normalization error \n", err)
+					continue
+				}
+
+				link := Link{
+					URL:       url,
+					Title:     strings.TrimSpace(s.AttrOr("title", "")),
+					Rel:       strings.Fields(s.AttrOr("rel", "")),
+					Hreflang:  strings.TrimSpace(s.AttrOr("hreflang", "")),
+					Element:   goquery.NodeName(s),
+					Attribute: rule.Attribute,
+					Kind:      rule.Kind,
+				}
+
+				// The text of elements such as <style> is where the URL was found, not its anchor text
+				if rule.Attribute != "" {
+					link.Text = linkText(s)
+				}
+
+				links = append(links, link)
+				elements = append(elements, order[s.Get(0)])
+			}
+		})
+	}
+
+	// Sort the links into document order. Links found in the same element
+	// keep the order of the rules they were found with
+	positions := make([]int, len(links))
+
+	for i := range positions {
+		positions[i] = i
+	}
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		return elements[positions[i]] < elements[positions[j]]
+	})
+
+	sorted := make([]Link, len(links))
+
+	for position, i := range positions {
+		sorted[position] = links[i]
+		sorted[position].Position = position
+	}
+
+	return sorted
+}
@@ -0,0 +1,51 @@
+package page
+
+import "strings"
+
+// Link is a URL found on a page, along with where and how it was found
+type Link struct {
+	// URL is the normalized URL the link points to
+	URL string
+
+	// Text is the link's anchor text, with its whitespace collapsed.
+	// The alt text of images is used for links without text
+	Text string
+
+	// Title is the link's title attribute
+	Title string
+
+	// Rel holds the values of the link's rel attribute e.g "nofollow"
+	Rel []string
+
+	// Hreflang is the language of the page the link points to, from its hreflang attribute e.g "fr-CA"
+	Hreflang string
+
+	// Element is the name of the element the URL was found in e.g `img`
+	Element string
+
+	// Attribute is the attribute the URL was found in e.g `src`.
+	// It is empty for URLs found in an element's text
+	Attribute string
+
+	// Kind is the kind of resource the URL points to
+	Kind Kind
+
+	// Position is the position of the link among the page's links, in document order
+	Position int
+}
+
+// HasRel checks if the link's rel attribute contains a value e.g "nofollow"
+func (l Link) HasRel(value string) bool {
+	for _, rel := range l.Rel {
+		if strings.EqualFold(rel, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NoFollow checks if the link asks crawlers not to follow it with rel=nofollow
+func (l Link) NoFollow() bool {
+	return l.HasRel("nofollow")
+}
@@ -1,20 +1,45 @@
 package page
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/darthchudi/crwl/fetcher"
+	"github.com/darthchudi/crwl/urlnorm"
 	"io"
 	"log"
 	netUrl "net/url"
 	"strings"
 )
 
+// Options configures how links are extracted from a page.
+// The zero value is ready to use
+type Options struct {
+	// Normalization configures how links are normalized
+	Normalization urlnorm.Options
+
+	// UserAgent is the crawler's user-agent. Robots <meta> tags and X-Robots-Tag
+	// headers scoped to it are applied in addition to those meant for every crawler
+	UserAgent string
+
+	// Rules are the rules URLs are extracted from the page with.
+	// `DefaultRules` are used if it is nil
+	Rules []Rule
+}
+
 type RawPage struct {
 	// URL is the page url
 	URL string
 
+	// Depth is the number of clicks it takes to get to the page
+	// from the web crawler url
+	Depth int
+
 	// Raw HTML of the page
 	Body []byte
+
+	// Response is the response the page was fetched with
+	Response *fetcher.Response
 }
 
 type Page struct {
@@ -25,42 +50,119 @@ type Page struct {
 	// URL is the page url
 	URL string
 
+	// Depth is the number of clicks it takes to get to the page
+	// from the web crawler url
+	Depth int
+
 	// Document is a goquery representation of the page HTML document
 	Document *goquery.Document
 
+	// Title is the text of the page's <title> element, with its whitespace collapsed
+	Title string
+
 	// AllURLs are all the links found in the page
 	AllURLs []string
 
 	// InternalURLs are links found in the page that belong to the
 	// same domain as the web crawler url
 	InternalURLs []string
+
+	// BaseURL is the URL relative links in the page are resolved against.
+	// It is the page's <base href> if it has one, otherwise the URL
+	// the page was served from
+	BaseURL string
+
+	// Redirects are the URLs the page's URL redirected to in order, ending
+	// with the URL the page was served from
+	Redirects []string
+
+	// CanonicalURL is the target of the page's rel=canonical link, if any
+	CanonicalURL string
+
+	// NoIndex is true if the page's robots directives ask crawlers not to index it
+	NoIndex bool
+
+	// NoFollow is true if the page's robots directives ask crawlers not to follow its links
+	NoFollow bool
+
+	// Links are every link found in the page in document order, including
+	// links to assets. A URL has a link for every place it is found in
+	Links []Link
+
+	// followURLs are the URLs linked to by at least one link without rel=nofollow
+	followURLs *Set
+
+	// options configures how links are extracted from the page
+	options Options
 }
 
 // NewPage creates a new page and populates it's links from its HTML
 // document
 func NewPage(parentURL, URL string, document *goquery.Document) Page {
+	return NewPageWithOptions(parentURL, URL, document, Options{})
+}
+
+// NewPageWithOptions creates a new page and populates it's links from its
+// HTML document according to the options
+func NewPageWithOptions(parentURL, URL string, document *goquery.Document, opts Options) Page {
+	return newPage(parentURL, URL, document, nil, opts)
+}
+
+// Parse parses a raw page into a page and populates it's links.
+// Relative links are resolved against the URL the page was served from, and
+// the X-Robots-Tag headers of the page's response are applied to the page
+func Parse(parentURL string, rawPage RawPage, opts Options) (Page, error) {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(rawPage.Body))
+
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := newPage(parentURL, rawPage.URL, document, rawPage.Response, opts)
+	page.Depth = rawPage.Depth
+
+	return page, nil
+}
+
+// newPage creates a new page from its HTML document and the response it
+// was fetched with, if any, and populates it's links
+func newPage(parentURL, URL string, document *goquery.Document, response *fetcher.Response, opts Options) Page {
 	page := Page{
-		ParentURL: parentURL, URL: URL, Document: document, AllURLs: []string{}, InternalURLs: []string{},
+		ParentURL: parentURL, URL: URL, Document: document, AllURLs: []string{}, InternalURLs: []string{}, BaseURL: URL, options: opts,
 	}
 
+	if response != nil {
+		if response.FinalURL != "" {
+			page.BaseURL = response.FinalURL
+		}
+
+		page.Redirects = response.Redirects
+
+		page.applyRobotsHeader(response.Header)
+	}
+
+	page.Title = strings.Join(strings.Fields(document.Find("title").First().Text()), " ")
+	page.readDirectives()
 	page.fetchLinks()
 
 	return page
 }
 
-// normalizeURL removes leading and trailing slashes in a URL
-func (p *Page) normalizeURL(url string) string {
-	// if the url is a relative url, normalize it
-	if strings.HasPrefix(url, "/") {
-		url = p.ParentURL + url
-	}
+// normalizeURL resolves a link against the page's base URL and normalizes it
+func (p *Page) normalizeURL(url string) (string, error) {
+	base := p.BaseURL
 
-	// if the url has a trailing slash, normalize it
-	if strings.HasSuffix(url, "/") {
-		url = url[:len(url)-1]
+	if base == "" {
+		base = p.URL
 	}
 
-	return url
+	return urlnorm.Resolve(base, url, p.options.Normalization)
+}
+
+// Follow checks if the crawler should follow a link to a URL found on the page.
+// Links are not followed if the page is nofollow, or if every link to the URL is rel=nofollow
+func (p *Page) Follow(url string) bool {
+	return !p.NoFollow && p.followURLs != nil && p.followURLs.Has(url)
 }
 
 // isURLSameDomain checks if a url belongs to the same domain
@@ -85,7 +187,9 @@ func (p *Page) isURLSameDomainAsParent(url string) (bool, error) {
 	return true, nil
 }
 
-// fetchLinks gets all URLs in the page and finds internal (local) URLs
+// fetchLinks gets all URLs in the page and finds internal (local) URLs.
+// AllURLs and InternalURLs only hold navigable pages, while every URL found
+// in the page is recorded in its links
 func (p *Page) fetchLinks() {
 	allURLs := []string{}
 	internalURLs := []string{}
@@ -93,15 +197,16 @@ func (p *Page) fetchLinks() {
 	// Used to deduplicate stored URLs
 	allURLsCache := NewSet()
 	internalURLsCache := NewSet()
+	p.followURLs = NewSet()
 
-	p.Document.Find("a").Each(func(i int, s *goquery.Selection) {
-		url, exists := s.Attr("href")
+	p.Links = p.extractLinks()
 
-		if !exists {
-			return
+	for _, link := range p.Links {
+		if link.Kind != KindPage {
+			continue
 		}
 
-		url = p.normalizeURL(url)
+		url := link.URL
 
 		// Only add this URL to the all URLs array if we haven't seen it before
 		if !allURLsCache.Has(url) {
@@ -109,15 +214,19 @@ func (p *Page) fetchLinks() {
 			allURLsCache.Add(url)
 		}
 
+		if !link.NoFollow() {
+			p.followURLs.Add(url)
+		}
+
 		isInternalURL, err := p.isURLSameDomainAsParent(url)
 
 		if err != nil {
 			log.Printf("🤠 %v Domain validation error \n", err)
-			return
+			continue
 		}
 
 		if !isInternalURL {
-			return
+			continue
 		}
 
 		// Only add this URL to the all URLs array only if we haven't added it before
@@ -125,22 +234,57 @@ func (p *Page) fetchLinks() {
 			internalURLs = append(internalURLs, url)
 			internalURLsCache.Add(url)
 		}
-
-		return
-	})
+	}
 
 	p.AllURLs = allURLs
 	p.InternalURLs = internalURLs
 }
 
+// Assets returns the URLs of the assets the page loads e.g images, scripts and stylesheets
+func (p *Page) Assets() []string {
+	assets := []string{}
+	seen := NewSet()
+
+	for _, link := range p.Links {
+		if link.Kind != KindAsset || seen.Has(link.URL) {
+			continue
+		}
+
+		assets = append(assets, link.URL)
+		seen.Add(link.URL)
+	}
+
+	return assets
+}
+
+// IsInternal checks if a URL found in the page belongs to the same
+// domain as the web crawler url
+func (p *Page) IsInternal(url string) bool {
+	isInternalURL, err := p.isURLSameDomainAsParent(url)
+
+	return err == nil && isInternalURL
+}
+
 // Print prints out all links in a page to a writer
 func (p *Page) Print(w io.Writer) {
 	message := fmt.Sprintf("✨ Extracted links in URL: %v \n", p.URL)
 
+	if p.CanonicalURL != "" && p.CanonicalURL != p.URL {
+		message += fmt.Sprintf("\t 🔗 Canonical URL: %v \n", p.CanonicalURL)
+	}
+
+	if p.NoIndex || p.NoFollow {
+		message += fmt.Sprintf("\t 🤖 noindex: %v, nofollow: %v \n", p.NoIndex, p.NoFollow)
+	}
+
 	for _, url := range p.AllURLs {
 		message += fmt.Sprintf("\t ✨ %v \n", url)
 	}
 
+	for _, url := range p.Assets() {
+		message += fmt.Sprintf("\t 🖼 %v \n", url)
+	}
+
 	message += "\n"
 
 	fmt.Fprint(w, message)
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/darthchudi/crwl/fetcher"
 	"html/template"
+	"net/http"
 	"strings"
 	"testing"
 )
@@ -37,6 +39,7 @@ func TestNewPage(t *testing.T) {
 		expectedAllURLs      int
 	}{
 		{htmlPath: "page.html", ParentURL: "https://example.com", URL: "https://example.com/privacy", expectedInternalURLs: 3, expectedAllURLs: 5},
+		{htmlPath: "relative.html", ParentURL: "https://example.com", URL: "https://example.com/loans/personal", expectedInternalURLs: 3, expectedAllURLs: 4},
 	}
 
 	for _, tc := range tests {
@@ -78,15 +81,196 @@ func TestNormalizeURL(t *testing.T) {
 	}{
 		{input: "/cards", want: "https://example.com/cards"},
 		{input: "https://example.com/help/", want: "https://example.com/help"},
+		{input: "personal", want: "https://example.com/personal"},
+		{input: "../business/", want: "https://example.com/business"},
+		{input: "//Example.com/press#latest", want: "https://example.com/press"},
 	}
 
 	page := Page{ParentURL: "https://example.com", URL: "https://example.com/loans"}
 
 	for _, tc := range tests {
-		result := page.normalizeURL(tc.input)
+		result, err := page.normalizeURL(tc.input)
+
+		if err != nil {
+			t.Fatalf("failed to normalize url %v: %v", tc.input, err)
+		}
 
 		if result != tc.want {
 			t.Fatalf("expected page to normalize url to %v, got %v", tc.want, result)
 		}
 	}
 }
+
+func TestParseDirectives(t *testing.T) {
+	body, err := LoadMockHTMLPage("directives.html")
+
+	if err != nil {
+		t.Fatalf("failed to load mock html: %v", err)
+	}
+
+	rawPage := RawPage{URL: "https://example.com/docs/start", Depth: 2, Body: body.Bytes()}
+
+	page, err := Parse("https://example.com", rawPage, Options{UserAgent: "crwl/1.0"})
+
+	if err != nil {
+		t.Fatalf("failed to parse page: %v", err)
+	}
+
+	if page.Depth != 2 {
+		t.Fatalf("expected page depth to be 2, got %v", page.Depth)
+	}
+
+	if page.Title != "Getting started" {
+		t.Fatalf("expected page title to be %q, got %q", "Getting started", page.Title)
+	}
+
+	// Links are resolved against the page's <base href>
+	expectedURLs := []string{"https://example.com/docs/install.html", "https://example.com/pricing", "https://example.com/careers"}
+
+	if strings.Join(page.AllURLs, " ") != strings.Join(expectedURLs, " ") {
+		t.Fatalf("expected page links to be %v, got %v", expectedURLs, page.AllURLs)
+	}
+
+	expectedCanonicalURL := "https://example.com/docs/getting-started"
+	if page.CanonicalURL != expectedCanonicalURL {
+		t.Fatalf("expected canonical url to be %v, got %v", expectedCanonicalURL, page.CanonicalURL)
+	}
+
+	// The googlebot meta tag doesn't apply to crwl
+	if !page.NoIndex || page.NoFollow {
+		t.Fatalf("expected page to be noindex and followed, got noindex: %v, nofollow: %v", page.NoIndex, page.NoFollow)
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://example.com/docs/install.html", want: true},
+		{url: "https://example.com/pricing", want: false},
+		// Followed because of the second link without rel=nofollow
+		{url: "https://example.com/careers", want: true},
+	}
+
+	for _, tc := range tests {
+		if page.Follow(tc.url) != tc.want {
+			t.Errorf("expected follow for %v to be %v, got %v", tc.url, tc.want, page.Follow(tc.url))
+		}
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	body, err := LoadMockHTMLPage("resources.html")
+
+	if err != nil {
+		t.Fatalf("failed to load mock html: %v", err)
+	}
+
+	rawPage := RawPage{URL: "https://example.com/", Body: body.Bytes()}
+
+	page, err := Parse("https://example.com", rawPage, Options{})
+
+	if err != nil {
+		t.Fatalf("failed to parse page: %v", err)
+	}
+
+	// Links are in document order
+	expected := []Link{
+		{URL: "https://example.com/css/main.css", Element: "link", Attribute: "href", Kind: KindAsset, Rel: []string{"stylesheet"}},
+		{URL: "https://example.com/favicon.ico", Element: "link", Attribute: "href", Kind: KindAsset, Rel: []string{"icon"}},
+		{URL: "https://example.com/fr", Element: "link", Attribute: "href", Kind: KindPage, Rel: []string{"alternate"}, Hreflang: "fr"},
+		{URL: "https://example.com/js/app.js", Element: "script", Attribute: "src", Kind: KindAsset},
+		{URL: "https://cdn.example.net/lib.js", Element: "script", Attribute: "src", Kind: KindAsset},
+		{URL: "https://example.com/css/fonts.css", Element: "style", Kind: KindAsset},
+		{URL: "https://example.com/img/hero.jpg", Element: "style", Kind: KindAsset},
+		{URL: "https://example.com/about", Text: "About us", Title: "Our story", Element: "a", Attribute: "href", Kind: KindPage},
+		{URL: "https://example.com/careers", Text: "Careers", Element: "a", Attribute: "href", Kind: KindPage, Rel: []string{"ugc", "nofollow"}},
+		{URL: "https://example.com", Text: "Home", Element: "a", Attribute: "href", Kind: KindPage},
+		{URL: "https://example.com/img/logo.png", Text: "Home", Element: "img", Attribute: "src", Kind: KindAsset},
+		{URL: "https://example.com/img/logo-small.png", Text: "Home", Element: "img", Attribute: "srcset", Kind: KindAsset},
+		{URL: "https://example.com/img/logo-large.png", Text: "Home", Element: "img", Attribute: "srcset", Kind: KindAsset},
+		{URL: "https://example.com/img/banner.webp", Element: "source", Attribute: "srcset", Kind: KindAsset},
+		{URL: "https://example.com/img/banner@2x.webp", Element: "source", Attribute: "srcset", Kind: KindAsset},
+		{URL: "https://example.com/img/pattern.png", Element: "div", Attribute: "style", Kind: KindAsset},
+		{URL: "https://example.com/embed/video", Element: "iframe", Attribute: "src", Kind: KindPage},
+		{URL: "https://example.com/search", Element: "form", Attribute: "action", Kind: KindForm},
+	}
+
+	if len(page.Links) != len(expected) {
+		t.Fatalf("expected page to have %v links, got %v: %+v", len(expected), len(page.Links), page.Links)
+	}
+
+	for i, link := range page.Links {
+		want := expected[i]
+		want.Position = i
+
+		if fmt.Sprintf("%+v", link) != fmt.Sprintf("%+v", want) {
+			t.Errorf("expected link %v to be %+v, got %+v", i, want, link)
+		}
+	}
+
+	if !page.Links[8].NoFollow() || page.Links[7].NoFollow() {
+		t.Errorf("expected only the careers link to be nofollow")
+	}
+
+	// Only navigable pages are in the page's URLs
+	expectedURLs := []string{"https://example.com/fr", "https://example.com/about", "https://example.com/careers", "https://example.com", "https://example.com/embed/video"}
+
+	if strings.Join(page.AllURLs, " ") != strings.Join(expectedURLs, " ") {
+		t.Fatalf("expected page links to be %v, got %v", expectedURLs, page.AllURLs)
+	}
+
+	if len(page.Assets()) != 12 {
+		t.Fatalf("expected page to have 12 assets, got %v", len(page.Assets()))
+	}
+
+	// Pages can be parsed with custom rules
+	page, err = Parse("https://example.com", rawPage, Options{Rules: []Rule{{Selector: "img[src]", Attribute: "src", Kind: KindAsset}}})
+
+	if err != nil {
+		t.Fatalf("failed to parse page: %v", err)
+	}
+
+	if len(page.Links) != 1 || len(page.AllURLs) != 0 {
+		t.Fatalf("expected page to have only 1 link, got %+v", page.Links)
+	}
+}
+
+func TestParseRobotsHeader(t *testing.T) {
+	tests := []struct {
+		header       []string
+		wantNoIndex  bool
+		wantNoFollow bool
+	}{
+		{header: []string{"nofollow"}, wantNoFollow: true},
+		{header: []string{"none"}, wantNoIndex: true, wantNoFollow: true},
+		{header: []string{"googlebot: noindex", "crwl: nofollow"}, wantNoFollow: true},
+		{header: []string{"unavailable_after: 25 Jun 2010 15:00:00 PST, noindex"}, wantNoIndex: true},
+		{header: []string{"all"}},
+	}
+
+	for _, tc := range tests {
+		response := &fetcher.Response{
+			URL:      "https://example.com/loans",
+			FinalURL: "https://example.com/loans/",
+			Header:   http.Header{"X-Robots-Tag": tc.header},
+		}
+
+		rawPage := RawPage{URL: response.URL, Body: []byte(`<a href="personal">Personal</a>`), Response: response}
+
+		page, err := Parse("https://example.com", rawPage, Options{UserAgent: "crwl"})
+
+		if err != nil {
+			t.Fatalf("failed to parse page: %v", err)
+		}
+
+		if page.NoIndex != tc.wantNoIndex || page.NoFollow != tc.wantNoFollow {
+			t.Errorf("expected X-Robots-Tag %v to give noindex: %v, nofollow: %v, got %v, %v", tc.header, tc.wantNoIndex, tc.wantNoFollow, page.NoIndex, page.NoFollow)
+		}
+
+		// Links are resolved against the URL the page was served from
+		expectedURL := "https://example.com/loans/personal"
+		if len(page.AllURLs) != 1 || page.AllURLs[0] != expectedURL {
+			t.Errorf("expected page links to be [%v], got %v", expectedURL, page.AllURLs)
+		}
+	}
+}
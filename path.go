@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/darthchudi/crwl/graph"
+	"log"
+	"os"
+)
+
+// runPath crawls a site and prints the shortest chain of links from one page to another:
+//
+//	crwl path https://example.com https://example.com/loans/personal
+//
+// The crawl starts from the first page unless a --url flag is given
+func runPath(args []string) {
+	fs := flag.NewFlagSet("path", flag.ExitOnError)
+	flags := newCrawlFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: crwl path [flags] <from> <to>\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	from, to := fs.Arg(0), fs.Arg(1)
+
+	urlSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "url" {
+			urlSet = true
+		}
+	})
+
+	if !urlSet {
+		*flags.crawlURL = from
+	}
+
+	c := flags.crawler()
+	c.LogWriter = os.Stderr
+
+	flags.crawl(c, os.Stderr)
+	fmt.Fprintln(os.Stderr)
+
+	path, err := c.Graph.ShortestPath(from, to)
+
+	if errors.Is(err, graph.ErrNoPath) {
+		fmt.Printf("🏝 %v\n", err)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		log.Fatalf("🥞 Failed to find path: %v", err)
+	}
+
+	fmt.Println(from)
+
+	clicks := 0
+
+	for _, edge := range path {
+		if edge.Kind == graph.KindRedirect {
+			fmt.Printf("\t ↪ redirect %v\n", edge.To)
+			continue
+		}
+
+		clicks++
+		fmt.Printf("\t → %q %v\n", edge.Link.Text, edge.To)
+	}
+
+	fmt.Printf("%v clicks\n", clicks)
+}
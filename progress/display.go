@@ -0,0 +1,156 @@
+package progress
+
+import (
+	"fmt"
+	"github.com/darthchudi/crwl/stats"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// liveInterval is how often a live display is refreshed
+	liveInterval = 250 * time.Millisecond
+
+	// plainInterval is how often a plain display writes a line
+	plainInterval = 10 * time.Second
+
+	// window is how far back throughput is measured from
+	window = 10 * time.Second
+)
+
+// clearLine moves the cursor to the start of the line and clears it
+const clearLine = "\r\033[K"
+
+// IsTerminal checks if a writer is a terminal, so a display can refresh in place on it
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Display shows a crawl's progress on a writer while it runs. On a terminal, a status line
+// is refreshed in place, and anything written to the display is written above it.
+// Otherwise a plain line is written every few seconds.
+// A Display is safe to write to from several goroutines
+type Display struct {
+	stats *stats.Stats
+	w     io.Writer
+	live  bool
+
+	// samples are the samples taken within the window, oldest first
+	samples []Sample
+
+	// line is the status line on a live display
+	line string
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewDisplay creates a display of a crawl's stats on a writer. The status
+// line is refreshed in place if live is true, which requires a terminal
+func NewDisplay(s *stats.Stats, w io.Writer, live bool) *Display {
+	return &Display{stats: s, w: w, live: live, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Start starts refreshing the display until it is stopped
+func (d *Display) Start() {
+	interval := plainInterval
+
+	if d.live {
+		interval = liveInterval
+	}
+
+	d.sample()
+
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				d.refresh()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the display and clears its status line
+func (d *Display) Stop() {
+	close(d.stop)
+	<-d.done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.live && d.line != "" {
+		io.WriteString(d.w, clearLine)
+		d.line = ""
+	}
+}
+
+// Write writes to the display's writer. On a live display the status line
+// is cleared first, and redrawn after
+func (d *Display) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.live || d.line == "" {
+		return d.w.Write(p)
+	}
+
+	io.WriteString(d.w, clearLine)
+	n, err := d.w.Write(p)
+	io.WriteString(d.w, d.line)
+
+	return n, err
+}
+
+// sample takes a sample of the crawl's stats, dropping samples that have left the
+// window, and returns the crawl's progress since the oldest sample in the window
+func (d *Display) sample() Progress {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	latest := Take(d.stats)
+	d.samples = append(d.samples, latest)
+
+	// Keep the newest sample that has left the window, so progress
+	// is always measured over the whole window
+	for len(d.samples) > 2 && latest.At.Sub(d.samples[1].At) >= window {
+		d.samples = d.samples[1:]
+	}
+
+	return Measure(d.samples[0], latest)
+}
+
+// refresh redraws the status line of a live display, or writes a line to a plain display
+func (d *Display) refresh() {
+	progress := d.sample()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.live {
+		log.New(d.w, "", log.LstdFlags).Printf("⏳ %v", progress)
+		return
+	}
+
+	d.line = fmt.Sprintf("⏳ %v", progress)
+	io.WriteString(d.w, clearLine+d.line)
+}
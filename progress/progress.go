@@ -0,0 +1,174 @@
+// progress measures a crawl's throughput from its stats and
+// displays it while the crawl runs
+package progress
+
+import (
+	"fmt"
+	"github.com/darthchudi/crwl/stats"
+	"strings"
+	"time"
+)
+
+// Sample is a reading of a crawl's stats at a point in time
+type Sample struct {
+	// At is when the sample was taken
+	At time.Time
+
+	// Total is the number of URLs the crawler has processed or queued
+	Total int64
+
+	// Pending is the number of URLs queued or being fetched
+	Pending int64
+
+	// InFlight is the number of requests being made
+	InFlight int64
+
+	// Done is the number of URLs that have completed, failed or been dropped
+	Done int64
+
+	// Failures is the number of URLs that failed
+	Failures int64
+
+	// Pages is the number of pages that were fetched and parsed
+	Pages int64
+
+	// Bytes is the number of bytes the crawler has downloaded
+	Bytes int64
+}
+
+// Take reads a sample of a crawl's stats
+func Take(s *stats.Stats) Sample {
+	return Sample{
+		At:       time.Now(),
+		Total:    s.Total(),
+		Pending:  s.Pending(),
+		InFlight: s.InFlight(),
+		Done:     s.Completed() + s.Failures() + s.Abandoned() + s.Disallowed(),
+		Failures: s.Failures(),
+		Pages:    s.Pages(),
+		Bytes:    s.Bytes(),
+	}
+}
+
+// Progress describes how a crawl is progressing between two samples
+type Progress struct {
+	// PagesPerSecond is the number of pages fetched and parsed per second
+	PagesPerSecond float64
+
+	// BytesPerSecond is the number of bytes downloaded per second
+	BytesPerSecond float64
+
+	// Queued is the number of URLs waiting to be fetched or processed
+	Queued int64
+
+	// InFlight is the number of requests being made
+	InFlight int64
+
+	// ErrorRate is the fraction of URLs that failed out of the URLs that completed or failed
+	ErrorRate float64
+
+	// Remaining is an estimate of the number of URLs left to crawl, including
+	// the URLs that will be discovered by the URLs that are pending
+	Remaining int64
+
+	// Growing is true if URLs are being discovered faster than they are done, so
+	// the number of URLs left to crawl can't be estimated. Remaining is then the pending URLs
+	Growing bool
+
+	// ETA is an estimate of how long the crawl will take to finish. It is 0 if it can't be estimated
+	ETA time.Duration
+}
+
+// Measure returns the progress a crawl made between an earlier and a later sample
+func Measure(earlier, later Sample) Progress {
+	p := Progress{
+		Queued:    later.Pending - later.InFlight,
+		InFlight:  later.InFlight,
+		Remaining: later.Pending,
+	}
+
+	if p.Queued < 0 {
+		p.Queued = 0
+	}
+
+	elapsed := later.At.Sub(earlier.At).Seconds()
+
+	if elapsed > 0 {
+		p.PagesPerSecond = float64(later.Pages-earlier.Pages) / elapsed
+		p.BytesPerSecond = float64(later.Bytes-earlier.Bytes) / elapsed
+	}
+
+	// The error rate is measured over the whole crawl until URLs fail between the samples,
+	// so a single failure at the start of a crawl doesn't report a 100% error rate
+	failures, done := later.Failures-earlier.Failures, later.Done-earlier.Done
+
+	if failures == 0 {
+		failures, done = later.Failures, later.Done
+	}
+
+	if done > 0 {
+		p.ErrorRate = float64(failures) / float64(done)
+	}
+
+	if later.Done == earlier.Done {
+		return p
+	}
+
+	// Every URL that is done discovers this many new URLs on average. If it is less than
+	// one, the pending URLs will discover a geometric series of URLs that adds up to
+	// pending / (1 - discovered) URLs
+	discovered := float64(later.Total-earlier.Total) / float64(later.Done-earlier.Done)
+
+	if discovered >= 1 {
+		p.Growing = true
+		return p
+	}
+
+	p.Remaining = int64(float64(later.Pending) / (1 - discovered))
+
+	if elapsed > 0 {
+		donePerSecond := float64(later.Done-earlier.Done) / elapsed
+		p.ETA = time.Duration(float64(p.Remaining) / donePerSecond * float64(time.Second)).Round(time.Second)
+	}
+
+	return p
+}
+
+// String describes the progress on a single line
+func (p Progress) String() string {
+	parts := []string{
+		fmt.Sprintf("%.1f pages/s", p.PagesPerSecond),
+		fmt.Sprintf("%v queued", p.Queued),
+		fmt.Sprintf("%v in flight", p.InFlight),
+		fmt.Sprintf("%.1f%% errors", p.ErrorRate*100),
+		fmt.Sprintf("%v/s", formatBytes(p.BytesPerSecond)),
+	}
+
+	switch {
+	case p.Growing:
+		parts = append(parts, fmt.Sprintf("%v+ left and growing", p.Remaining))
+	case p.ETA > 0:
+		parts = append(parts, fmt.Sprintf("~%v left, ETA %v", p.Remaining, p.ETA))
+	default:
+		parts = append(parts, fmt.Sprintf("~%v left", p.Remaining))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// formatBytes formats a number of bytes with a binary unit e.g 1.5 MiB
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	unit := 0
+
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%.0f %v", bytes, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %v", bytes, units[unit])
+}
@@ -0,0 +1,129 @@
+package progress
+
+import (
+	"bytes"
+	"github.com/darthchudi/crwl/stats"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeasure(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	earlier := Sample{At: start, Total: 100, Pending: 40, InFlight: 10, Done: 60, Failures: 2, Pages: 50, Bytes: 1 << 20}
+
+	tests := []struct {
+		name     string
+		later    Sample
+		expected Progress
+	}{
+		{
+			// 50 URLs were done and 25 discovered in 10s, so every URL discovers half a URL and
+			// the 50 pending URLs will lead to 100 URLs in total, done at 5 URLs a second
+			name:  "shrinking",
+			later: Sample{At: start.Add(10 * time.Second), Total: 125, Pending: 50, InFlight: 20, Done: 110, Failures: 7, Pages: 90, Bytes: 3 << 20},
+			expected: Progress{
+				PagesPerSecond: 4,
+				BytesPerSecond: float64(2<<20) / 10,
+				Queued:         30,
+				InFlight:       20,
+				ErrorRate:      0.1,
+				Remaining:      100,
+				ETA:            20 * time.Second,
+			},
+		},
+		{
+			name:  "growing",
+			later: Sample{At: start.Add(10 * time.Second), Total: 200, Pending: 120, InFlight: 20, Done: 80, Failures: 2, Pages: 70, Bytes: 1 << 20},
+			expected: Progress{
+				PagesPerSecond: 2,
+				Queued:         100,
+				InFlight:       20,
+				ErrorRate:      2.0 / 80,
+				Remaining:      120,
+				Growing:        true,
+			},
+		},
+		{
+			name:  "stalled",
+			later: Sample{At: start.Add(10 * time.Second), Total: 100, Pending: 40, InFlight: 40, Done: 60, Failures: 2, Pages: 50, Bytes: 1 << 20},
+			expected: Progress{
+				InFlight:  40,
+				ErrorRate: 2.0 / 60,
+				Remaining: 40,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		if progress := Measure(earlier, tc.later); progress != tc.expected {
+			t.Errorf("%v: expected progress %+v, got %+v", tc.name, tc.expected, progress)
+		}
+	}
+}
+
+func TestProgressString(t *testing.T) {
+	progress := Progress{PagesPerSecond: 4, BytesPerSecond: 1536 * 1024, Queued: 30, InFlight: 20, ErrorRate: 0.1, Remaining: 100, ETA: 20 * time.Second}
+	expected := "4.0 pages/s · 30 queued · 20 in flight · 10.0% errors · 1.5 MiB/s · ~100 left, ETA 20s"
+
+	if progress.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, progress.String())
+	}
+
+	progress = Progress{Remaining: 120, Growing: true}
+
+	if !strings.HasSuffix(progress.String(), "0 B/s · 120+ left and growing") {
+		t.Fatalf("unexpected progress %q", progress.String())
+	}
+}
+
+func TestLiveDisplay(t *testing.T) {
+	var b bytes.Buffer
+
+	display := NewDisplay(stats.NewStats(), &b, true)
+	display.sample()
+
+	// Nothing is cleared until there is a status line
+	display.Write([]byte("first\n"))
+	display.refresh()
+	display.Write([]byte("second\n"))
+
+	line := "⏳ " + Measure(Sample{}, Sample{}).String()
+	expected := "first\n" + clearLine + line + clearLine + "second\n" + line
+
+	if b.String() != expected {
+		t.Fatalf("expected output %q, got %q", expected, b.String())
+	}
+}
+
+func TestPlainDisplay(t *testing.T) {
+	var b bytes.Buffer
+
+	display := NewDisplay(stats.NewStats(), &b, false)
+	display.sample()
+	display.Write([]byte("page\n"))
+	display.refresh()
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+
+	if len(lines) != 2 || lines[0] != "page" || !strings.Contains(lines[1], "⏳ 0.0 pages/s") || strings.Contains(b.String(), clearLine) {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	file, err := ioutil.TempFile("", "progress")
+
+	if err != nil {
+		t.Fatalf("create error: %v", err)
+	}
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if IsTerminal(file) || IsTerminal(&bytes.Buffer{}) {
+		t.Fatalf("expected files and buffers not to be terminals")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/darthchudi/crwl/graph"
+	"log"
+	"os"
+)
+
+// runRank crawls a site and prints the PageRank and HITS scores of its pages,
+// from the highest PageRank to the lowest:
+//
+//	crwl rank --url=https://example.com --top=20
+func runRank(args []string) {
+	fs := flag.NewFlagSet("rank", flag.ExitOnError)
+	flags := newCrawlFlags(fs)
+	defaults := graph.DefaultRankOptions()
+	damping := fs.Float64("damping", defaults.Damping, "Probability that a visitor follows a link instead of jumping to a random page")
+	iterations := fs.Int("iterations", defaults.Iterations, "Maximum number of iterations to compute scores with")
+	tolerance := fs.Float64("tolerance", defaults.Tolerance, "Stop computing scores once they change by less than this between iterations")
+	top := fs.Int("top", 0, "Number of pages to print (0 means every page)")
+
+	fs.Parse(args)
+
+	if *damping < 0 || *damping > 1 {
+		log.Fatalf("🥞 Invalid --damping flag: %v must be between 0 and 1", *damping)
+	}
+
+	c := flags.crawler()
+	c.LogWriter = os.Stderr
+
+	flags.crawl(c, os.Stderr)
+	fmt.Fprintln(os.Stderr)
+
+	scores := c.Graph.Scores(graph.RankOptions{Damping: *damping, Iterations: *iterations, Tolerance: *tolerance})
+
+	if *top > 0 && *top < len(scores) {
+		scores = scores[:*top]
+	}
+
+	if err := graph.WriteScores(os.Stdout, scores); err != nil {
+		log.Fatalf("🥞 Failed to write scores: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+// ratelimit provides a per-host rate limiter that keeps
+// the crawler polite to the sites it crawls
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket is the token bucket of a single host
+type bucket struct {
+	// tokens is the number of requests that can be made to the host without waiting.
+	// It is negative when requests have been scheduled ahead of the refill rate
+	tokens float64
+
+	// refilled is when tokens were last refilled
+	refilled time.Time
+
+	// next is the time at which the last scheduled request will be made
+	next time.Time
+
+	// delay is the minimum amount of time between requests to the host
+	delay time.Duration
+
+	// pausedUntil is the time until which no requests should be made to the host
+	pausedUntil time.Time
+}
+
+// Limiter limits the rate of requests made to each host using a token bucket
+// per host, and enforces a minimum delay between requests to a host.
+// It is safe for concurrent use
+type Limiter struct {
+	// rate is the number of requests per second allowed to each host.
+	// A rate of 0 means requests are not rate limited
+	rate float64
+
+	// burst is the number of requests that can be made to a host at once
+	burst int
+
+	// hosts maps a host to its token bucket
+	hosts map[string]*bucket
+
+	// mu protects the hosts map and buckets
+	mu sync.Mutex
+}
+
+// NewLimiter initializes a new limiter that allows a number of requests per second
+// to each host, with bursts of up to burst requests.
+// A rate of 0 means requests are only limited by host delays and pauses
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:  rate,
+		burst: burst,
+		hosts: make(map[string]*bucket),
+	}
+}
+
+// bucket returns the token bucket for a host. It must be called with the lock held
+func (l *Limiter) bucket(host string) *bucket {
+	b, exists := l.hosts[host]
+
+	if !exists {
+		b = &bucket{tokens: float64(l.burst)}
+		l.hosts[host] = b
+	}
+
+	return b
+}
+
+// reserve schedules a request to a host and returns when it can be made
+func (l *Limiter) reserve(host string) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(host)
+	now := time.Now()
+
+	// The request can't be made before the host's pause is over
+	// or the host's delay since the last request has passed
+	at := now
+
+	if b.pausedUntil.After(at) {
+		at = b.pausedUntil
+	}
+
+	if b.delay > 0 && b.next.Add(b.delay).After(at) {
+		at = b.next.Add(b.delay)
+	}
+
+	if l.rate > 0 {
+		// Refill the bucket up to the time of the request
+		if b.refilled.IsZero() {
+			b.refilled = at
+		}
+
+		if elapsed := at.Sub(b.refilled); elapsed > 0 {
+			b.tokens += elapsed.Seconds() * l.rate
+			b.refilled = at
+		}
+
+		if b.tokens > float64(l.burst) {
+			b.tokens = float64(l.burst)
+		}
+
+		b.tokens--
+
+		// Wait for the bucket to refill if there are no tokens left
+		if b.tokens < 0 {
+			at = at.Add(time.Duration(-b.tokens / l.rate * float64(time.Second)))
+		}
+	}
+
+	if at.After(b.next) {
+		b.next = at
+	}
+
+	return at
+}
+
+// Wait blocks until a request can be made to a host.
+// It returns an error if the context is cancelled before then.
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	wait := time.Until(l.reserve(host))
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetDelay sets the minimum amount of time between requests to a host
+// e.g from the host's robots.txt Crawl-delay
+func (l *Limiter) SetDelay(host string, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.bucket(host).delay = delay
+}
+
+// Pause stops requests to a host for a duration
+// e.g when the host responds with a Retry-After header
+func (l *Limiter) Pause(host string, duration time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(host)
+	until := time.Now().Add(duration)
+
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitN makes n requests to a host and returns how long they took
+func waitN(t *testing.T, l *Limiter, host string, n int) time.Duration {
+	start := time.Now()
+
+	for i := 0; i < n; i++ {
+		if err := l.Wait(context.Background(), host); err != nil {
+			t.Fatalf("wait error: %v", err)
+		}
+	}
+
+	return time.Since(start)
+}
+
+func TestWaitRate(t *testing.T) {
+	// 5 requests with a burst of 1 at 100 requests per second should take at least 40ms
+	l := NewLimiter(100, 1)
+
+	elapsed := waitN(t, l, "example.com", 5)
+
+	if elapsed < time.Millisecond*40 {
+		t.Fatalf("expected requests to take at least 40ms, took %v", elapsed)
+	}
+
+	// Other hosts have their own buckets
+	elapsed = waitN(t, l, "test.com", 1)
+
+	if elapsed > time.Millisecond*10 {
+		t.Fatalf("expected request to another host not to wait, took %v", elapsed)
+	}
+}
+
+func TestWaitBurst(t *testing.T) {
+	l := NewLimiter(1, 5)
+
+	elapsed := waitN(t, l, "example.com", 5)
+
+	if elapsed > time.Millisecond*10 {
+		t.Fatalf("expected burst requests not to wait, took %v", elapsed)
+	}
+}
+
+func TestSetDelay(t *testing.T) {
+	l := NewLimiter(0, 1)
+	l.SetDelay("example.com", time.Millisecond*20)
+
+	elapsed := waitN(t, l, "example.com", 3)
+
+	if elapsed < time.Millisecond*40 {
+		t.Fatalf("expected requests to take at least 40ms, took %v", elapsed)
+	}
+}
+
+func TestPause(t *testing.T) {
+	l := NewLimiter(0, 1)
+	l.Pause("example.com", time.Millisecond*30)
+
+	elapsed := waitN(t, l, "example.com", 1)
+
+	if elapsed < time.Millisecond*30 {
+		t.Fatalf("expected request to wait for the pause, took %v", elapsed)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	l := NewLimiter(0, 1)
+	l.Pause("example.com", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	err := l.Wait(ctx, "example.com")
+
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected wait to return %v, got %v", context.DeadlineExceeded, err)
+	}
+}
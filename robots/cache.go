@@ -0,0 +1,139 @@
+package robots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/darthchudi/crwl/fetcher"
+	netUrl "net/url"
+	"sync"
+)
+
+// entry holds the robots.txt rules of a single host
+type entry struct {
+	// done is closed once the host's robots.txt has been fetched
+	done chan struct{}
+
+	// robots are the host's rules
+	robots *Robots
+
+	// err is the error the host's robots.txt couldn't be fetched with
+	err error
+}
+
+// Cache fetches and caches the robots.txt rules of hosts.
+// It is safe for concurrent use
+type Cache struct {
+	// fetcher fetches robots.txt files
+	fetcher fetcher.ResponseFetcher
+
+	// hosts maps a scheme and host to its robots.txt rules
+	hosts map[string]*entry
+
+	// mu protects the hosts map
+	mu sync.Mutex
+}
+
+// NewCache initializes a new robots.txt cache which fetches files with a fetcher
+func NewCache(f fetcher.ResponseFetcher) *Cache {
+	return &Cache{
+		fetcher: f,
+		hosts:   make(map[string]*entry),
+	}
+}
+
+// Get returns the robots.txt rules for the host of a URL.
+// The host's robots.txt is fetched the first time it is needed, and
+// callers that need it while it is being fetched wait for the result.
+//
+// If the host has no robots.txt (a 4xx response) every path on the host is allowed,
+// and if it responds with a server error every path is disallowed. If the request fails,
+// every path is allowed but the result isn't cached, so the host's robots.txt is fetched
+// again the next time it is needed. The context's error is returned if it is done.
+func (c *Cache) Get(ctx context.Context, url string) (*Robots, error) {
+	parsedURL, err := netUrl.Parse(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%v://%v", parsedURL.Scheme, parsedURL.Host)
+
+	for {
+		c.mu.Lock()
+		e, exists := c.hosts[key]
+
+		if !exists {
+			e = &entry{done: make(chan struct{})}
+			c.hosts[key] = e
+		}
+		c.mu.Unlock()
+
+		if !exists {
+			c.fetch(ctx, key, e)
+		}
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		switch {
+		case e.err == nil:
+			return e.robots, nil
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case exists && isContextError(e.err):
+			// The fetch we waited for was stopped by its caller's context, so fetch it ourselves
+			continue
+		default:
+			return AllowAll(), nil
+		}
+	}
+}
+
+// fetch fetches the robots.txt of a host into its entry. Entries of
+// failed requests are removed from the cache so they are fetched again
+func (c *Cache) fetch(ctx context.Context, key string, e *entry) {
+	defer close(e.done)
+
+	response, err := c.fetcher.FetchResponse(ctx, key+"/robots.txt")
+
+	switch {
+	case err != nil:
+		e.err = err
+
+		c.mu.Lock()
+		delete(c.hosts, key)
+		c.mu.Unlock()
+	case response.StatusCode >= 500:
+		e.robots = DisallowAll()
+	case response.Successful():
+		e.robots = Parse(response.Body)
+	default:
+		e.robots = AllowAll()
+	}
+}
+
+// isContextError checks if an error was caused by a context being cancelled or timing out
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Allowed checks if a user-agent is allowed to crawl a URL
+func (c *Cache) Allowed(ctx context.Context, userAgent, url string) (bool, error) {
+	robots, err := c.Get(ctx, url)
+
+	if err != nil {
+		return false, err
+	}
+
+	parsedURL, err := netUrl.Parse(url)
+
+	if err != nil {
+		return false, err
+	}
+
+	return robots.Allowed(userAgent, parsedURL.RequestURI()), nil
+}
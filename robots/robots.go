@@ -0,0 +1,273 @@
+// robots parses robots.txt files and decides which URLs
+// a crawler is allowed to fetch
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule is an Allow or Disallow line in a robots.txt group
+type rule struct {
+	// allow is true for Allow rules and false for Disallow rules
+	allow bool
+
+	// pattern is the path pattern of the rule.
+	// It can contain `*` wildcards and end with a `$` anchor
+	pattern string
+}
+
+// group is a set of rules that apply to one or more user-agents
+type group struct {
+	// userAgents are the lowercased user-agents the group applies to
+	userAgents []string
+
+	// rules are the group's Allow and Disallow rules
+	rules []rule
+
+	// crawlDelay is the minimum amount of time a crawler should
+	// wait between requests to the host
+	crawlDelay time.Duration
+}
+
+// Robots holds the rules parsed from a robots.txt file
+type Robots struct {
+	// groups are the groups found in the file
+	groups []*group
+
+	// disallowAll disallows every path regardless of the groups
+	disallowAll bool
+}
+
+// AllowAll returns rules that allow every path to be crawled.
+// It is used for hosts that don't have a robots.txt file
+func AllowAll() *Robots {
+	return &Robots{}
+}
+
+// DisallowAll returns rules that disallow every path from being crawled.
+// It is used for hosts whose robots.txt is unavailable because of a server error
+func DisallowAll() *Robots {
+	return &Robots{disallowAll: true}
+}
+
+// Parse parses the body of a robots.txt file.
+// Lines that can't be understood are ignored.
+func Parse(body []byte) *Robots {
+	robots := &Robots{}
+
+	var current *group
+
+	// readingAgents is true while we are reading the user-agent lines at the start of a group
+	readingAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Remove comments
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		i := strings.Index(line, ":")
+
+		if i < 0 {
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after a group's rules starts a new group
+			if !readingAgents {
+				current = &group{}
+				robots.groups = append(robots.groups, current)
+				readingAgents = true
+			}
+
+			current.userAgents = append(current.userAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			readingAgents = false
+
+			// Rules outside of a group don't apply to anyone
+			if current == nil {
+				continue
+			}
+
+			// An empty Disallow rule allows everything, which is already the default
+			if value == "" {
+				continue
+			}
+
+			current.rules = append(current.rules, rule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			readingAgents = false
+
+			if current == nil {
+				continue
+			}
+
+			seconds, err := strconv.ParseFloat(value, 64)
+
+			if err != nil || seconds < 0 {
+				continue
+			}
+
+			current.crawlDelay = time.Duration(seconds * float64(time.Second))
+		default:
+			// Other lines such as Sitemap don't end the list of user-agents of a group
+			continue
+		}
+	}
+
+	return robots
+}
+
+// ProductToken returns the lowercased name of a crawler from its user-agent
+// e.g "crwl" for "crwl/1.0 (+https://example.com)"
+func ProductToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	return token
+}
+
+// groupsFor returns the groups that apply to a user-agent.
+// These are every group naming the user-agent or, if no group names
+// the user-agent, the `*` groups.
+func (r *Robots) groupsFor(userAgent string) []*group {
+	token := ProductToken(userAgent)
+
+	matched := []*group{}
+	wildcard := []*group{}
+
+	for _, g := range r.groups {
+		for _, agent := range g.userAgents {
+			if agent == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+
+			if agent == token {
+				matched = append(matched, g)
+				break
+			}
+		}
+	}
+
+	if len(matched) > 0 {
+		return matched
+	}
+
+	return wildcard
+}
+
+// rulesFor returns the combined rules of the groups that apply to a user-agent
+func (r *Robots) rulesFor(userAgent string) []rule {
+	rules := []rule{}
+
+	for _, g := range r.groupsFor(userAgent) {
+		rules = append(rules, g.rules...)
+	}
+
+	return rules
+}
+
+// CrawlDelay returns the minimum amount of time a user-agent should wait
+// between requests to the host. It is 0 if the host doesn't set a delay.
+func (r *Robots) CrawlDelay(userAgent string) time.Duration {
+	var delay time.Duration
+
+	for _, g := range r.groupsFor(userAgent) {
+		if g.crawlDelay > delay {
+			delay = g.crawlDelay
+		}
+	}
+
+	return delay
+}
+
+// Allowed checks if a user-agent is allowed to crawl a path.
+// The path should include the URL's query string, if any.
+//
+// The rule with the longest matching pattern decides if the path is allowed.
+// If an Allow and a Disallow rule are equally long, the Allow rule wins.
+func (r *Robots) Allowed(userAgent, path string) bool {
+	if r.disallowAll {
+		return false
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	allowed := true
+	longest := -1
+
+	for _, rule := range r.rulesFor(userAgent) {
+		if !match(rule.pattern, path) {
+			continue
+		}
+
+		length := len(rule.pattern)
+
+		if length > longest || (length == longest && rule.allow) {
+			longest = length
+			allowed = rule.allow
+		}
+	}
+
+	return allowed
+}
+
+// match checks if a path matches a robots.txt pattern.
+// `*` matches any sequence of characters and a trailing `$` anchors the
+// pattern to the end of the path. Otherwise patterns match path prefixes.
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part of the pattern must be a prefix of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	position := len(parts[0])
+	last := len(parts) - 1
+
+	for i, part := range parts[1:] {
+		// An anchored pattern must end with its last part
+		if anchored && i+1 == last {
+			return len(path)-len(part) >= position && strings.HasSuffix(path, part)
+		}
+
+		index := strings.Index(path[position:], part)
+
+		if index < 0 {
+			return false
+		}
+
+		position += index + len(part)
+	}
+
+	if anchored {
+		return position == len(path)
+	}
+
+	return true
+}
@@ -0,0 +1,236 @@
+package robots
+
+import (
+	"context"
+	"errors"
+	"github.com/darthchudi/crwl/fetcher"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `
+# Rules for every crawler
+User-agent: *
+Disallow: /admin
+Disallow: /*.pdf$
+Allow: /admin/public
+
+# Rules for crwl
+User-agent: crwl
+User-agent: another-bot
+Crawl-delay: 1.5
+Disallow: /private
+Allow: /private/press
+Disallow: /search?*q=
+Sitemap: https://example.com/sitemap.xml
+
+User-agent: blocked-bot
+Disallow: /
+`
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{userAgent: "some-bot", path: "/", want: true},
+		{userAgent: "some-bot", path: "/admin", want: false},
+		{userAgent: "some-bot", path: "/admin/users", want: false},
+		{userAgent: "some-bot", path: "/admin/public", want: true},
+		{userAgent: "some-bot", path: "/files/report.pdf", want: false},
+		{userAgent: "some-bot", path: "/files/report.pdf?download=1", want: true},
+		{userAgent: "crwl/1.0", path: "/admin", want: true},
+		{userAgent: "crwl", path: "/private/team", want: false},
+		{userAgent: "CRWL", path: "/private/press/2021", want: true},
+		{userAgent: "crwl", path: "/search?page=1&q=loans", want: false},
+		{userAgent: "crwl", path: "/search?page=1", want: true},
+		{userAgent: "another-bot", path: "/private", want: false},
+		{userAgent: "blocked-bot", path: "/", want: false},
+		{userAgent: "blocked-bot", path: "", want: false},
+	}
+
+	robots := Parse([]byte(robotsTxt))
+
+	for _, tc := range tests {
+		got := robots.Allowed(tc.userAgent, tc.path)
+
+		if got != tc.want {
+			t.Errorf("expected %v to be allowed for %v to be %v, got %v", tc.path, tc.userAgent, tc.want, got)
+		}
+	}
+}
+
+func TestCrawlDelay(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      time.Duration
+	}{
+		{userAgent: "crwl", want: time.Millisecond * 1500},
+		{userAgent: "some-bot", want: 0},
+		{userAgent: "blocked-bot", want: 0},
+	}
+
+	robots := Parse([]byte(robotsTxt))
+
+	for _, tc := range tests {
+		got := robots.CrawlDelay(tc.userAgent)
+
+		if got != tc.want {
+			t.Errorf("expected crawl delay for %v to be %v, got %v", tc.userAgent, tc.want, got)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "/fish", path: "/fish.html", want: true},
+		{pattern: "/fish", path: "/Fish.asp", want: false},
+		{pattern: "/fish*", path: "/fishheads/yummy.html", want: true},
+		{pattern: "/*.php", path: "/folder/filename.php?parameters", want: true},
+		{pattern: "/*.php$", path: "/filename.php", want: true},
+		{pattern: "/*.php$", path: "/filename.php?parameters", want: false},
+		{pattern: "/fish*.php", path: "/fishheads/catfish.php?parameters", want: true},
+		{pattern: "/fish*.php", path: "/Fish.PHP", want: false},
+		{pattern: "/exact$", path: "/exact", want: true},
+		{pattern: "/exact$", path: "/exactly", want: false},
+		{pattern: "/a*b*c$", path: "/abc", want: true},
+		{pattern: "/a*bc$", path: "/abc/abc", want: true},
+	}
+
+	for _, tc := range tests {
+		got := match(tc.pattern, tc.path)
+
+		if got != tc.want {
+			t.Errorf("expected pattern %v matching %v to be %v, got %v", tc.pattern, tc.path, tc.want, got)
+		}
+	}
+}
+
+// mockFetcher returns a canned robots.txt response and counts its requests
+type mockFetcher struct {
+	statusCode int
+	body       []byte
+	err        error
+	requests   int
+}
+
+func (f *mockFetcher) FetchResponse(ctx context.Context, url string) (*fetcher.Response, error) {
+	f.requests++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &fetcher.Response{URL: url, StatusCode: f.statusCode, Body: f.body}, nil
+}
+
+func TestCache(t *testing.T) {
+	f := &mockFetcher{statusCode: 200, body: []byte(robotsTxt)}
+	cache := NewCache(f)
+
+	allowed, err := cache.Allowed(context.Background(), "crwl", "https://example.com/private/team")
+
+	if err != nil {
+		t.Fatalf("cache error: %v", err)
+	}
+
+	if allowed {
+		t.Fatalf("expected url to be disallowed")
+	}
+
+	allowed, err = cache.Allowed(context.Background(), "crwl", "https://example.com/about")
+
+	if err != nil {
+		t.Fatalf("cache error: %v", err)
+	}
+
+	if !allowed {
+		t.Fatalf("expected url to be allowed")
+	}
+
+	// We expect the host's robots.txt to be fetched once
+	if f.requests != 1 {
+		t.Fatalf("expected robots.txt to be fetched once, got %v", f.requests)
+	}
+}
+
+func TestCacheUnavailable(t *testing.T) {
+	tests := []struct {
+		fetcher *mockFetcher
+		want    bool
+	}{
+		{fetcher: &mockFetcher{statusCode: 404}, want: true},
+		{fetcher: &mockFetcher{statusCode: 403}, want: true},
+		{fetcher: &mockFetcher{err: errors.New("connection refused")}, want: true},
+		{fetcher: &mockFetcher{statusCode: 503}, want: false},
+	}
+
+	for _, tc := range tests {
+		cache := NewCache(tc.fetcher)
+
+		allowed, err := cache.Allowed(context.Background(), "crwl", "https://example.com/about")
+
+		if err != nil {
+			t.Fatalf("cache error: %v", err)
+		}
+
+		if allowed != tc.want {
+			t.Errorf("expected url to be allowed to be %v for fetch status %v and error %v, got %v", tc.want, tc.fetcher.statusCode, tc.fetcher.err, allowed)
+		}
+	}
+}
+
+func TestCacheFailure(t *testing.T) {
+	f := &mockFetcher{err: errors.New("connection reset by peer")}
+	cache := NewCache(f)
+
+	url := "https://example.com/private/team"
+
+	// Every path is allowed while robots.txt can't be fetched
+	if allowed, err := cache.Allowed(context.Background(), "crwl", url); err != nil || !allowed {
+		t.Fatalf("expected url to be allowed, got %v and error %v", allowed, err)
+	}
+
+	// Failed fetches aren't cached, so robots.txt is fetched again once the host recovers
+	f.err, f.statusCode, f.body = nil, 200, []byte(robotsTxt)
+
+	if allowed, err := cache.Allowed(context.Background(), "crwl", url); err != nil || allowed {
+		t.Fatalf("expected url to be disallowed, got %v and error %v", allowed, err)
+	}
+
+	if f.requests != 2 {
+		t.Fatalf("expected robots.txt to be fetched twice, got %v", f.requests)
+	}
+}
+
+func TestCacheCancelled(t *testing.T) {
+	f := &mockFetcher{statusCode: 200, body: []byte(robotsTxt)}
+	cache := NewCache(f)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	f.err = ctx.Err()
+
+	if _, err := cache.Get(ctx, "https://example.com/about"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the context's error, got %v", err)
+	}
+
+	// A fetch stopped by its caller's context isn't cached
+	f.err = nil
+
+	robots, err := cache.Get(context.Background(), "https://example.com/about")
+
+	if err != nil {
+		t.Fatalf("cache error: %v", err)
+	}
+
+	if robots.Allowed("crwl", "/private/team") || f.requests != 2 {
+		t.Fatalf("expected robots.txt to be fetched again, got %v requests", f.requests)
+	}
+}
@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// failedURLsPerCategory is the number of failed URLs kept for each category.
+// Failures past it are counted but their URLs aren't kept
+const failedURLsPerCategory = 100
+
+// Failure is a URL that failed
+type Failure struct {
+	// Category describes why the URL failed e.g "timeout" or "http_4xx"
+	Category string
+
+	// URL is the URL that failed
+	URL string
+
+	// Referrer is the URL of the page the URL was first found on
+	Referrer string
+
+	// StatusCode is the HTTP status code the URL was served with, or 0 if there was no response
+	StatusCode int
+}
+
+// FailuresByCategory returns the number of URLs that failed in each category, keyed by category
+func (s *Stats) FailuresByCategory() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := make(map[string]int64, len(s.failuresByCategory))
+
+	for category, count := range s.failuresByCategory {
+		categories[category] = count
+	}
+
+	return categories
+}
+
+// FailuresByStatusCode returns the number of URLs that failed with each HTTP status code,
+// keyed by status code. URLs that failed without a response are left out
+func (s *Stats) FailuresByStatusCode() map[int]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make(map[int]int64, len(s.failuresByStatusCode))
+
+	for code, count := range s.failuresByStatusCode {
+		codes[code] = count
+	}
+
+	return codes
+}
+
+// FailureGroup is the URLs that failed in a category
+type FailureGroup struct {
+	// Category is the category the URLs failed in
+	Category string
+
+	// Count is the number of URLs that failed in the category
+	Count int64
+
+	// Failures are the first URLs that failed in the category, sorted by status code and URL.
+	// There are fewer of them than Count if some URLs weren't kept
+	Failures []Failure
+}
+
+// FailureReport lists the URLs that failed, grouped by category
+type FailureReport struct {
+	// Groups are the categories URLs failed in, with the largest first
+	Groups []FailureGroup
+}
+
+// FailureReport groups the URLs that failed by category
+func (s *Stats) FailureReport() FailureReport {
+	groups := make(map[string][]Failure)
+
+	for _, failure := range s.FailedURLs() {
+		groups[failure.Category] = append(groups[failure.Category], failure)
+	}
+
+	report := FailureReport{Groups: []FailureGroup{}}
+
+	for category, count := range s.FailuresByCategory() {
+		failures := groups[category]
+
+		sort.Slice(failures, func(i, j int) bool {
+			if failures[i].StatusCode != failures[j].StatusCode {
+				return failures[i].StatusCode < failures[j].StatusCode
+			}
+
+			return failures[i].URL < failures[j].URL
+		})
+
+		report.Groups = append(report.Groups, FailureGroup{Category: category, Count: count, Failures: failures})
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Count != report.Groups[j].Count {
+			return report.Groups[i].Count > report.Groups[j].Count
+		}
+
+		return report.Groups[i].Category < report.Groups[j].Category
+	})
+
+	return report
+}
+
+// Write writes the report to a writer
+func (r FailureReport) Write(w io.Writer) error {
+	if len(r.Groups) == 0 {
+		return nil
+	}
+
+	message := "🥞 Failed URLs by category:\n"
+
+	for _, group := range r.Groups {
+		message += fmt.Sprintf("\t %v (%v):\n", group.Category, group.Count)
+
+		for _, failure := range group.Failures {
+			message += "\t\t "
+
+			if failure.StatusCode != 0 {
+				message += fmt.Sprintf("%v ", failure.StatusCode)
+			}
+
+			message += failure.URL
+
+			if failure.Referrer != "" {
+				message += fmt.Sprintf(" (found on %v)", failure.Referrer)
+			}
+
+			message += "\n"
+		}
+
+		if left := group.Count - int64(len(group.Failures)); left > 0 {
+			message += fmt.Sprintf("\t\t ... and %v more\n", left)
+		}
+	}
+
+	_, err := io.WriteString(w, message)
+	return err
+}
@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the buckets fetch latencies are counted in
+var DefaultLatencyBuckets = []time.Duration{
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a thread-safe count of durations in buckets
+type Histogram struct {
+	// bounds are the upper bounds of the buckets, in increasing order
+	bounds []time.Duration
+
+	// counts are the number of durations in each bucket, with a
+	// last bucket for durations greater than every bound
+	counts []int64
+
+	// sum is the total of every duration observed
+	sum time.Duration
+
+	// count is the number of durations observed
+	count int64
+
+	// max is the longest duration observed
+	max time.Duration
+
+	mu sync.Mutex
+}
+
+// NewHistogram creates a histogram with buckets with upper bounds in increasing order
+func NewHistogram(bounds []time.Duration) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]int64, len(bounds)+1)}
+}
+
+// Observe counts a duration in the first bucket whose upper bound it doesn't exceed
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucket := len(h.bounds)
+
+	for i, bound := range h.bounds {
+		if d <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	h.counts[bucket]++
+	h.sum += d
+	h.count++
+
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Bucket is the number of durations a histogram observed up to an upper bound
+type Bucket struct {
+	// UpperBound is the largest duration counted in the bucket
+	UpperBound time.Duration
+
+	// Count is the number of durations that don't exceed the upper bound,
+	// including the durations counted in smaller buckets
+	Count int64
+}
+
+// Buckets returns the histogram's cumulative buckets in increasing order. Durations
+// greater than every bound are only included in the histogram's count
+func (h *Histogram) Buckets() []Bucket {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make([]Bucket, len(h.bounds))
+	var count int64
+
+	for i, bound := range h.bounds {
+		count += h.counts[i]
+		buckets[i] = Bucket{UpperBound: bound, Count: count}
+	}
+
+	return buckets
+}
+
+// Count returns the number of durations the histogram observed
+func (h *Histogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count
+}
+
+// Sum returns the total of every duration the histogram observed
+func (h *Histogram) Sum() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.sum
+}
+
+// Quantile estimates the duration a fraction q of the observed durations don't exceed
+// e.g 0.9 for the 90th percentile. Durations are assumed to be spread evenly within their
+// bucket, and the longest duration observed is used as the upper bound of the last bucket.
+// It returns 0 if no durations have been observed
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := q * float64(h.count)
+	var lower time.Duration
+	var count int64
+
+	for i, bucketCount := range h.counts {
+		upper := h.max
+
+		if i < len(h.bounds) && h.bounds[i] < h.max {
+			upper = h.bounds[i]
+		}
+
+		if bucketCount > 0 && float64(count+bucketCount) >= rank {
+			fraction := (rank - float64(count)) / float64(bucketCount)
+			return lower + time.Duration(fraction*float64(upper-lower))
+		}
+
+		count += bucketCount
+
+		if upper > lower {
+			lower = upper
+		}
+	}
+
+	return h.max
+}
@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelEscaper escapes the values of Prometheus labels
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// prometheusWriter writes metrics in the Prometheus text exposition format,
+// keeping the first error that occurs
+type prometheusWriter struct {
+	w   io.Writer
+	err error
+}
+
+// metric writes the help and type lines of a metric
+func (p *prometheusWriter) metric(name, kind, help string) {
+	p.printf("# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+}
+
+// sample writes a sample of a metric with a list of formatted labels
+func (p *prometheusWriter) sample(name string, labels []string, value float64) {
+	if len(labels) == 0 {
+		p.printf("%v %v\n", name, formatFloat(value))
+		return
+	}
+
+	p.printf("%v{%v} %v\n", name, strings.Join(labels, ","), formatFloat(value))
+}
+
+// single writes a metric with a single sample
+func (p *prometheusWriter) single(name, kind, help string, value int64) {
+	p.metric(name, kind, help)
+	p.sample(name, nil, float64(value))
+}
+
+// codes writes a counter with a sample for each HTTP status code, in order
+func (p *prometheusWriter) codes(name, help string, counts map[int]int64) {
+	codes := make([]int, 0, len(counts))
+
+	for code := range counts {
+		codes = append(codes, code)
+	}
+
+	sort.Ints(codes)
+
+	p.metric(name, "counter", help)
+
+	for _, code := range codes {
+		p.sample(name, []string{label("code", strconv.Itoa(code))}, float64(counts[code]))
+	}
+}
+
+// histogram writes the samples of a histogram of durations in seconds with a list of formatted labels
+func (p *prometheusWriter) histogram(name string, labels []string, h *Histogram) {
+	for _, bucket := range h.Buckets() {
+		p.sample(name+"_bucket", append(labels, label("le", formatFloat(bucket.UpperBound.Seconds()))), float64(bucket.Count))
+	}
+
+	p.sample(name+"_bucket", append(labels, label("le", "+Inf")), float64(h.Count()))
+	p.sample(name+"_sum", labels, h.Sum().Seconds())
+	p.sample(name+"_count", labels, float64(h.Count()))
+}
+
+// label formats a label
+func label(name, value string) string {
+	return fmt.Sprintf("%v=\"%v\"", name, labelEscaper.Replace(value))
+}
+
+func (p *prometheusWriter) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// formatFloat formats a sample value the way Prometheus expects
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WritePrometheus writes the crawler's stats to a writer in the Prometheus text exposition format
+func (s *Stats) WritePrometheus(w io.Writer) error {
+	p := &prometheusWriter{w: w}
+
+	p.single("crwl_urls_total", "counter", "Number of URLs the crawler has processed.", s.Total())
+	p.single("crwl_urls_pending", "gauge", "Number of URLs queued or being fetched.", s.Pending())
+	p.single("crwl_urls_completed_total", "counter", "Number of URLs that were fetched and processed.", s.Completed())
+	p.single("crwl_urls_failed_total", "counter", "Number of URLs that failed somewhere in the crawl pipeline.", s.Failures())
+	p.single("crwl_urls_abandoned_total", "counter", "Number of URLs that were dropped because the crawl was stopped.", s.Abandoned())
+	p.single("crwl_urls_disallowed_total", "counter", "Number of URLs that robots.txt disallows.", s.Disallowed())
+	p.single("crwl_urls_unfetched_total", "counter", "Number of URLs that were discovered but never fetched.", s.Unfetched())
+	p.single("crwl_pages_fetched_total", "counter", "Number of pages that were fetched and parsed.", s.Pages())
+	p.single("crwl_assets_total", "counter", "Number of asset URLs the crawler has checked.", s.Assets())
+	p.single("crwl_assets_broken_total", "counter", "Number of asset URLs that could not be fetched.", s.BrokenAssets())
+	p.single("crwl_downloaded_bytes_total", "counter", "Number of bytes the crawler has downloaded.", s.Bytes())
+	p.single("crwl_retries_total", "counter", "Number of times a failed fetch was retried.", s.Retries())
+	p.single("crwl_redirects_total", "counter", "Number of fetched URLs that redirected to another URL.", s.Redirects())
+
+	categories := s.FailuresByCategory()
+	names := make([]string, 0, len(categories))
+
+	for category := range categories {
+		names = append(names, category)
+	}
+
+	sort.Strings(names)
+
+	p.metric("crwl_failures_total", "counter", "Number of URLs that failed by category.")
+
+	for _, category := range names {
+		p.sample("crwl_failures_total", []string{label("category", category)}, float64(categories[category]))
+	}
+
+	p.codes("crwl_failed_responses_total", "Number of URLs that failed by HTTP status code.", s.FailuresByStatusCode())
+	p.codes("crwl_responses_total", "Number of responses by HTTP status code.", s.StatusCodes())
+
+	p.metric("crwl_fetch_duration_seconds", "histogram", "How long requests to fetch URLs took.")
+	p.histogram("crwl_fetch_duration_seconds", nil, s.Latency())
+
+	p.metric("crwl_phase_duration_seconds", "histogram", "How long each phase of fetching and parsing URLs took.")
+
+	for _, phase := range Phases {
+		if h := s.timings.phase(phase); h.Count() > 0 {
+			p.histogram("crwl_phase_duration_seconds", []string{label("phase", string(phase))}, h)
+		}
+	}
+
+	return p.err
+}
+
+// Handler returns a HTTP handler that serves the crawler's stats in the Prometheus
+// text exposition format, so they can be scraped while the crawl runs
+func (s *Stats) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		s.WritePrometheus(w)
+	})
+}
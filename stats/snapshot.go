@@ -0,0 +1,205 @@
+package stats
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Snapshot is a machine-readable copy of a crawler's stats at a point in time
+type Snapshot struct {
+	// At is when the snapshot was taken
+	At time.Time `json:"at"`
+
+	// ElapsedSeconds is how long the crawler had been crawling for when the snapshot was taken
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+
+	Total        int64 `json:"total"`
+	Pending      int64 `json:"pending"`
+	InFlight     int64 `json:"in_flight"`
+	Completed    int64 `json:"completed"`
+	Failures     int64 `json:"failures"`
+	Abandoned    int64 `json:"abandoned"`
+	Disallowed   int64 `json:"disallowed"`
+	Unfetched    int64 `json:"unfetched"`
+	Pages        int64 `json:"pages"`
+	Bytes        int64 `json:"bytes"`
+	Retries      int64 `json:"retries"`
+	Flaky        int64 `json:"flaky"`
+	Redirects    int64 `json:"redirects"`
+	Assets       int64 `json:"assets"`
+	BrokenAssets int64 `json:"broken_assets"`
+
+	// PagesPerSecond and BytesPerSecond are the throughput since the previous snapshot
+	// in a time series, or since the crawl started for a single snapshot
+	PagesPerSecond float64 `json:"pages_per_second"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+
+	// FailuresByCategory is the number of URLs that failed in each category
+	FailuresByCategory map[string]int64 `json:"failures_by_category"`
+
+	// FailuresByStatusCode is the number of URLs that failed with each HTTP status code
+	FailuresByStatusCode map[int]int64 `json:"failures_by_status_code"`
+
+	// StatusCodes is the number of responses with each HTTP status code
+	StatusCodes map[int]int64 `json:"status_codes"`
+
+	// Latency summarizes how long requests to fetch URLs took
+	Latency LatencySummary `json:"latency"`
+
+	// StopReason is the reason the crawl ended early, if it did
+	StopReason string `json:"stop_reason,omitempty"`
+}
+
+// LatencySummary summarizes a histogram of durations in seconds
+type LatencySummary struct {
+	Count int64   `json:"count"`
+	P50   float64 `json:"p50_seconds"`
+	P90   float64 `json:"p90_seconds"`
+	P99   float64 `json:"p99_seconds"`
+}
+
+// Snapshot returns a copy of the crawler's stats
+func (s *Stats) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		At:             time.Now(),
+		ElapsedSeconds: s.Elapsed().Seconds(),
+
+		Total:        s.Total(),
+		Pending:      s.Pending(),
+		InFlight:     s.InFlight(),
+		Completed:    s.Completed(),
+		Failures:     s.Failures(),
+		Abandoned:    s.Abandoned(),
+		Disallowed:   s.Disallowed(),
+		Unfetched:    s.Unfetched(),
+		Pages:        s.Pages(),
+		Bytes:        s.Bytes(),
+		Retries:      s.Retries(),
+		Flaky:        s.Flaky(),
+		Redirects:    s.Redirects(),
+		Assets:       s.Assets(),
+		BrokenAssets: s.BrokenAssets(),
+
+		FailuresByCategory:   s.FailuresByCategory(),
+		FailuresByStatusCode: s.FailuresByStatusCode(),
+		StatusCodes:          s.StatusCodes(),
+		Latency: LatencySummary{
+			Count: s.latency.Count(),
+			P50:   s.latency.Quantile(0.5).Seconds(),
+			P90:   s.latency.Quantile(0.9).Seconds(),
+			P99:   s.latency.Quantile(0.99).Seconds(),
+		},
+		StopReason: s.StopReason(),
+	}
+
+	if snapshot.ElapsedSeconds > 0 {
+		snapshot.PagesPerSecond = float64(snapshot.Pages) / snapshot.ElapsedSeconds
+		snapshot.BytesPerSecond = float64(snapshot.Bytes) / snapshot.ElapsedSeconds
+	}
+
+	return snapshot
+}
+
+// WriteJSON writes the snapshot to a writer as JSON
+func (s Snapshot) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(s)
+}
+
+// Recorder records a time series of snapshots of a crawler's stats at an interval
+type Recorder struct {
+	stats    *Stats
+	interval time.Duration
+
+	// series are the snapshots taken so far, oldest first
+	series []Snapshot
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRecorder creates a recorder that takes a snapshot of a crawler's stats every interval
+func NewRecorder(s *Stats, interval time.Duration) *Recorder {
+	return &Recorder{stats: s, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Start starts taking snapshots until the recorder is stopped
+func (r *Recorder) Start() {
+	r.record()
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.record()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops taking snapshots, and takes a final snapshot
+func (r *Recorder) Stop() {
+	close(r.stop)
+	<-r.done
+
+	r.record()
+}
+
+// record takes a snapshot, measuring throughput since the previous snapshot
+func (r *Recorder) record() {
+	snapshot := r.stats.Snapshot()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.series) > 0 {
+		previous := r.series[len(r.series)-1]
+		snapshot.PagesPerSecond, snapshot.BytesPerSecond = 0, 0
+
+		if elapsed := snapshot.At.Sub(previous.At).Seconds(); elapsed > 0 {
+			snapshot.PagesPerSecond = float64(snapshot.Pages-previous.Pages) / elapsed
+			snapshot.BytesPerSecond = float64(snapshot.Bytes-previous.Bytes) / elapsed
+		}
+	}
+
+	r.series = append(r.series, snapshot)
+}
+
+// Series returns the snapshots taken so far, oldest first
+func (r *Recorder) Series() []Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Snapshot{}, r.series...)
+}
+
+// recording is the JSON a recorder's snapshots are written as
+type recording struct {
+	IntervalSeconds float64    `json:"interval_seconds"`
+	Final           Snapshot   `json:"final"`
+	Series          []Snapshot `json:"series"`
+}
+
+// WriteJSON writes the recorder's snapshots to a writer as a JSON object holding
+// the interval they were taken at, the final snapshot and the time series of every snapshot
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	series := r.Series()
+	final := r.stats.Snapshot()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(recording{IntervalSeconds: r.interval.Seconds(), Final: final, Series: series})
+}
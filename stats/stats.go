@@ -4,6 +4,7 @@ package stats
 
 import (
 	"log"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -23,6 +24,69 @@ type Stats struct {
 	// the crawl pipeline
 	failures int64
 
+	// abandoned is the number of requests that were dropped before
+	// they completed because the crawl was stopped
+	abandoned int64
+
+	// disallowed is the number of URLs that were not fetched
+	// because the site's robots.txt disallows them
+	disallowed int64
+
+	// unfetched is the number of URLs that were discovered but
+	// never fetched because the crawl was stopped or limited
+	unfetched int64
+
+	// bytes is the number of bytes the crawler has downloaded
+	bytes int64
+
+	// retries is the number of times a failed fetch was retried
+	retries int64
+
+	// flaky is the number of URLs that were fetched after one or more failed attempts
+	flaky int64
+
+	// redirects is the number of fetched URLs that redirected to another URL
+	redirects int64
+
+	// assets is the number of asset URLs e.g images and scripts the crawler has checked
+	assets int64
+
+	// brokenAssets is the number of asset URLs that could not be fetched
+	brokenAssets int64
+
+	// pages is the number of pages that were fetched and parsed
+	pages int64
+
+	// inFlight is the number of requests that are being made
+	inFlight int64
+
+	// stopReason is the reason the crawl ended before every discovered
+	// URL was fetched. It is empty if the crawl ran to completion
+	stopReason string
+
+	// failed holds the first URLs that failed in each category, in the order they failed.
+	// At most failedURLsPerCategory URLs are kept for a category, so long crawls with
+	// many failures don't hold on to all of them
+	failed []Failure
+
+	// failuresByCategory is the number of URLs that failed in each category, keyed by category
+	failuresByCategory map[string]int64
+
+	// failuresByStatusCode is the number of URLs that failed with each HTTP status code, keyed by status code
+	failuresByStatusCode map[int]int64
+
+	// statusCodes is the number of responses with each HTTP status code, keyed by status code
+	statusCodes map[int]int64
+
+	// mu protects the stop reason, failed URLs and their counts, status codes, start time and duration
+	mu sync.Mutex
+
+	// latency counts how long each request to fetch a URL took
+	latency *Histogram
+
+	// timings counts how long each phase of fetching and processing URLs took
+	timings *timings
+
 	// startTime is a record of when the crawler began crawling
 	startTime time.Time
 
@@ -33,10 +97,19 @@ type Stats struct {
 // NewStats creates a new stats structure
 func NewStats() *Stats {
 	return &Stats{
-		total:     0,
-		pending:   0,
-		completed: 0,
-		failures:  0,
+		total:      0,
+		pending:    0,
+		completed:  0,
+		failures:   0,
+		abandoned:  0,
+		disallowed: 0,
+
+		failed:               []Failure{},
+		failuresByCategory:   make(map[string]int64),
+		failuresByStatusCode: make(map[int]int64),
+		statusCodes:          make(map[int]int64),
+		latency:              NewHistogram(DefaultLatencyBuckets),
+		timings:              newTimings(),
 	}
 }
 
@@ -61,8 +134,119 @@ func (s *Stats) RecordOperationCompletion() {
 	atomic.AddInt64(&s.completed, 1)
 }
 
+// RecordOperationAbandoned updates the internal counters to indicate a
+// crawler operation that was dropped before it completed
+func (s *Stats) RecordOperationAbandoned() {
+	atomic.AddInt64(&s.pending, -1)
+	atomic.AddInt64(&s.abandoned, 1)
+}
+
+// RecordOperationDisallowed updates the internal counters to indicate a
+// crawler operation that was dropped because robots.txt disallows its URL
+func (s *Stats) RecordOperationDisallowed() {
+	atomic.AddInt64(&s.pending, -1)
+	atomic.AddInt64(&s.disallowed, 1)
+}
+
+// RecordUnfetched records URLs that were discovered but never fetched
+func (s *Stats) RecordUnfetched(n int64) {
+	atomic.AddInt64(&s.unfetched, n)
+}
+
+// RecordBytes records bytes downloaded by the crawler
+func (s *Stats) RecordBytes(n int64) {
+	atomic.AddInt64(&s.bytes, n)
+}
+
+// RecordRetry records a retry of a failed fetch
+func (s *Stats) RecordRetry() {
+	atomic.AddInt64(&s.retries, 1)
+}
+
+// RecordFlaky records a URL that was fetched after one or more failed attempts
+func (s *Stats) RecordFlaky() {
+	atomic.AddInt64(&s.flaky, 1)
+}
+
+// RecordRedirect records a fetched URL that redirected to another URL
+func (s *Stats) RecordRedirect() {
+	atomic.AddInt64(&s.redirects, 1)
+}
+
+// RecordAsset records a new operation that checks an asset URL.
+// It is recorded in addition to the operation itself
+func (s *Stats) RecordAsset() {
+	atomic.AddInt64(&s.assets, 1)
+}
+
+// RecordBrokenAsset records an asset URL that could not be fetched
+func (s *Stats) RecordBrokenAsset() {
+	atomic.AddInt64(&s.brokenAssets, 1)
+}
+
+// RecordPage records a page that was fetched and parsed
+func (s *Stats) RecordPage() {
+	atomic.AddInt64(&s.pages, 1)
+}
+
+// RecordRequestStart records the start of a request to fetch a URL
+func (s *Stats) RecordRequestStart() {
+	atomic.AddInt64(&s.inFlight, 1)
+}
+
+// RecordRequestEnd records the end of a request to fetch a URL, whether it succeeded or not
+func (s *Stats) RecordRequestEnd() {
+	atomic.AddInt64(&s.inFlight, -1)
+}
+
+// RecordFailure records a URL that failed, so failures can be broken down by category
+// and status code. It is recorded in addition to the operation itself
+func (s *Stats) RecordFailure(failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failuresByCategory[failure.Category]++
+
+	if failure.StatusCode != 0 {
+		s.failuresByStatusCode[failure.StatusCode]++
+	}
+
+	if s.failuresByCategory[failure.Category] <= failedURLsPerCategory {
+		s.failed = append(s.failed, failure)
+	}
+}
+
+// RecordResponse records the HTTP status code of a response and how long the
+// request took. Every attempt to fetch a URL is recorded
+func (s *Stats) RecordResponse(statusCode int, latency time.Duration) {
+	s.mu.Lock()
+	s.statusCodes[statusCode]++
+	s.mu.Unlock()
+
+	s.latency.Observe(latency)
+}
+
+// RecordLatency records how long a request that got no response took
+func (s *Stats) RecordLatency(latency time.Duration) {
+	s.latency.Observe(latency)
+}
+
+// RecordStopReason records why the crawl ended early.
+// Only the first reason recorded is kept.
+func (s *Stats) RecordStopReason(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopReason == "" {
+		s.stopReason = reason
+	}
+}
+
 // RecordStartTime records the time the crawler began crawling
 func (s *Stats) RecordStartTime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Only set the start time if it hasn't been set before
 	if s.startTime.IsZero() {
 		s.startTime = time.Now()
@@ -73,6 +257,9 @@ func (s *Stats) RecordStartTime() {
 // RecordTotalDuration records the total time it took for the
 // crawler to complete all operations
 func (s *Stats) RecordTotalDuration() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Only record the duration if the start time has been set
 	if s.startTime.IsZero() {
 		return
@@ -106,18 +293,144 @@ func (s *Stats) Completed() int64 {
 	return atomic.LoadInt64(&s.completed)
 }
 
+// Abandoned returns a counter of the number of requests that were dropped
+// before they completed because the crawl was stopped
+func (s *Stats) Abandoned() int64 {
+	return atomic.LoadInt64(&s.abandoned)
+}
+
+// Disallowed returns a counter of the number of URLs that were not fetched
+// because the site's robots.txt disallows them
+func (s *Stats) Disallowed() int64 {
+	return atomic.LoadInt64(&s.disallowed)
+}
+
+// Unfetched returns a counter of the number of URLs that were discovered
+// but never fetched because the crawl was stopped or limited
+func (s *Stats) Unfetched() int64 {
+	return atomic.LoadInt64(&s.unfetched)
+}
+
+// Bytes returns the number of bytes the crawler has downloaded
+func (s *Stats) Bytes() int64 {
+	return atomic.LoadInt64(&s.bytes)
+}
+
+// Retries returns the number of times a failed fetch was retried
+func (s *Stats) Retries() int64 {
+	return atomic.LoadInt64(&s.retries)
+}
+
+// Flaky returns the number of URLs that were fetched after one or more failed attempts
+func (s *Stats) Flaky() int64 {
+	return atomic.LoadInt64(&s.flaky)
+}
+
+// Redirects returns the number of fetched URLs that redirected to another URL
+func (s *Stats) Redirects() int64 {
+	return atomic.LoadInt64(&s.redirects)
+}
+
+// Assets returns the number of asset URLs the crawler has checked
+func (s *Stats) Assets() int64 {
+	return atomic.LoadInt64(&s.assets)
+}
+
+// BrokenAssets returns the number of asset URLs that could not be fetched
+func (s *Stats) BrokenAssets() int64 {
+	return atomic.LoadInt64(&s.brokenAssets)
+}
+
+// Pages returns the number of pages that were fetched and parsed
+func (s *Stats) Pages() int64 {
+	return atomic.LoadInt64(&s.pages)
+}
+
+// InFlight returns the number of requests that are being made
+func (s *Stats) InFlight() int64 {
+	return atomic.LoadInt64(&s.inFlight)
+}
+
+// FailedURLs returns the first URLs that failed in each category, in the order they failed.
+// Up to failedURLsPerCategory URLs are returned for a category
+func (s *Stats) FailedURLs() []Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Failure{}, s.failed...)
+}
+
+// StatusCodes returns the number of responses with each HTTP status code, keyed by status code
+func (s *Stats) StatusCodes() map[int]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make(map[int]int64, len(s.statusCodes))
+
+	for code, count := range s.statusCodes {
+		codes[code] = count
+	}
+
+	return codes
+}
+
+// Latency returns the histogram of how long each request to fetch a URL took
+func (s *Stats) Latency() *Histogram {
+	return s.latency
+}
+
+// StopReason returns the reason the crawl ended before every discovered URL
+// was fetched, or an empty string if the crawl ran to completion
+func (s *Stats) StopReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopReason
+}
+
 // Duration returns the total time it took for the web crawler to crawl
 func (s *Stats) Duration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return s.duration
 }
 
+// Elapsed returns how long the crawler has been crawling for. It is the total
+// duration once the crawl has finished, and 0 before it has started
+func (s *Stats) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.duration != 0 || s.startTime.IsZero() {
+		return s.duration
+	}
+
+	return time.Since(s.startTime)
+}
+
 // Print prints out the crawler's operation stats
 func (s *Stats) Print() {
 	log.Printf(
-		"✨ Total: %v. Pending: %v. Completed: %v. Failed: %v",
+		"✨ Total: %v. Pending: %v. Completed: %v. Failed: %v. Abandoned: %v. Disallowed: %v. Unfetched: %v. Retries: %v. Flaky: %v. Redirects: %v. Assets: %v. Broken assets: %v. Bytes: %v",
 		s.Total(),
 		s.Pending(),
 		s.Completed(),
 		s.Failures(),
+		s.Abandoned(),
+		s.Disallowed(),
+		s.Unfetched(),
+		s.Retries(),
+		s.Flaky(),
+		s.Redirects(),
+		s.Assets(),
+		s.BrokenAssets(),
+		s.Bytes(),
 	)
+
+	if reason := s.StopReason(); reason != "" {
+		log.Printf("🛑 Crawl stopped early: %v", reason)
+	}
+
+	s.PrintTimings()
 }
@@ -1,8 +1,13 @@
 package stats
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestRecordNewOperation(t *testing.T) {
@@ -89,6 +94,80 @@ func TestRecordOperationCompletion(t *testing.T) {
 	}
 }
 
+func TestRecordOperationAbandoned(t *testing.T) {
+	s := NewStats()
+
+	expected := 150
+
+	// Set fake pending tasks
+	s.pending = int64(expected)
+
+	wg := sync.WaitGroup{}
+	wg.Add(expected)
+
+	for i := 0; i < expected; i++ {
+		go func() {
+			s.RecordOperationAbandoned()
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if s.Abandoned() != int64(expected) {
+		t.Fatalf("expected abandoned operations to be %v, got %v", expected, s.Abandoned())
+	}
+
+	if s.Pending() != 0 {
+		t.Fatalf("expected 0 pending operations, got %v", s.Pending())
+	}
+}
+
+func TestRecordOperationDisallowed(t *testing.T) {
+	s := NewStats()
+
+	expected := 50
+
+	// Set fake pending tasks
+	s.pending = int64(expected)
+
+	wg := sync.WaitGroup{}
+	wg.Add(expected)
+
+	for i := 0; i < expected; i++ {
+		go func() {
+			s.RecordOperationDisallowed()
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+
+	if s.Disallowed() != int64(expected) {
+		t.Fatalf("expected disallowed operations to be %v, got %v", expected, s.Disallowed())
+	}
+
+	if s.Pending() != 0 {
+		t.Fatalf("expected 0 pending operations, got %v", s.Pending())
+	}
+}
+
+func TestRecordStopReason(t *testing.T) {
+	s := NewStats()
+
+	if s.StopReason() != "" {
+		t.Fatalf("expected no stop reason, got %v", s.StopReason())
+	}
+
+	s.RecordStopReason("max pages")
+	s.RecordStopReason("cancelled")
+
+	// We expect that only the first stop reason is kept
+	if s.StopReason() != "max pages" {
+		t.Fatalf("expected stop reason to be %v, got %v", "max pages", s.StopReason())
+	}
+}
+
 func TestRecordStartTime(t *testing.T) {
 	s := NewStats()
 
@@ -109,3 +188,324 @@ func TestRecordTotalDuration(t *testing.T) {
 		t.Fatalf("expected total duration to be set")
 	}
 }
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]time.Duration{time.Millisecond, time.Second})
+
+	for _, d := range []time.Duration{time.Microsecond, time.Millisecond, 10 * time.Millisecond, time.Minute} {
+		h.Observe(d)
+	}
+
+	buckets := h.Buckets()
+
+	// Buckets are cumulative, and durations beyond the last bound are only counted in the total
+	if len(buckets) != 2 || buckets[0].Count != 2 || buckets[1].Count != 3 || h.Count() != 4 {
+		t.Fatalf("unexpected buckets %+v with count %v", buckets, h.Count())
+	}
+
+	if expected := time.Minute + 11*time.Millisecond + time.Microsecond; h.Sum() != expected {
+		t.Fatalf("expected sum to be %v, got %v", expected, h.Sum())
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	s := NewStats()
+
+	s.RecordNewOperation()
+	s.RecordNewOperation()
+	s.RecordOperationCompletion()
+	s.RecordPage()
+	s.RecordBytes(2048)
+	s.RecordResponse(200, 20*time.Millisecond)
+	s.RecordResponse(404, 3*time.Second)
+	s.RecordFailure(Failure{Category: "timeout", URL: "https://example.com/slow"})
+	s.RecordFailure(Failure{Category: "http_4xx", URL: "https://example.com/missing", StatusCode: 404})
+
+	var b bytes.Buffer
+
+	if err := s.WritePrometheus(&b); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	expected := []string{
+		"# TYPE crwl_urls_total counter\ncrwl_urls_total 2\n",
+		"# TYPE crwl_urls_pending gauge\ncrwl_urls_pending 1\n",
+		"crwl_pages_fetched_total 1\n",
+		"crwl_downloaded_bytes_total 2048\n",
+		`crwl_failures_total{category="http_4xx"} 1` + "\n" + `crwl_failures_total{category="timeout"} 1` + "\n",
+		`crwl_failed_responses_total{code="404"} 1` + "\n",
+		`crwl_responses_total{code="200"} 1` + "\n" + `crwl_responses_total{code="404"} 1` + "\n",
+		`crwl_fetch_duration_seconds_bucket{le="0.025"} 1` + "\n",
+		`crwl_fetch_duration_seconds_bucket{le="5"} 2` + "\n",
+		`crwl_fetch_duration_seconds_bucket{le="+Inf"} 2` + "\n",
+		"crwl_fetch_duration_seconds_sum 3.02\ncrwl_fetch_duration_seconds_count 2\n",
+	}
+
+	for _, metric := range expected {
+		if !strings.Contains(b.String(), metric) {
+			t.Errorf("expected metrics to contain %q, got:\n%v", metric, b.String())
+		}
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h := NewHistogram([]time.Duration{10 * time.Millisecond, 100 * time.Millisecond})
+
+	if h.Quantile(0.5) != 0 {
+		t.Fatalf("expected an empty histogram's quantiles to be 0, got %v", h.Quantile(0.5))
+	}
+
+	// 90 durations up to 10ms, 9 up to 100ms and one of 2s
+	for i := 0; i < 90; i++ {
+		h.Observe(5 * time.Millisecond)
+	}
+
+	for i := 0; i < 9; i++ {
+		h.Observe(50 * time.Millisecond)
+	}
+
+	h.Observe(2 * time.Second)
+
+	tests := []struct {
+		q        float64
+		expected time.Duration
+	}{
+		{q: 0.5, expected: 5555555 * time.Nanosecond},
+		{q: 0.9, expected: 10 * time.Millisecond},
+		{q: 0.99, expected: 100 * time.Millisecond},
+		{q: 1, expected: 2 * time.Second},
+	}
+
+	for _, tc := range tests {
+		if quantile := h.Quantile(tc.q); quantile != tc.expected {
+			t.Errorf("expected quantile %v to be %v, got %v", tc.q, tc.expected, quantile)
+		}
+	}
+}
+
+func TestURLPattern(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com":                     "example.com/",
+		"https://example.com/about":               "example.com/about",
+		"https://example.com/articles/2021/hello": "example.com/articles/*",
+		"https://example.com/2021/hello":          "example.com/:id/*",
+		"https://example.com/p123?page=2":         "example.com/:id",
+	}
+
+	for url, expected := range tests {
+		if pattern := URLPattern(url); pattern != expected {
+			t.Errorf("expected the pattern of %v to be %v, got %v", url, expected, pattern)
+		}
+	}
+}
+
+func TestRecordPhase(t *testing.T) {
+	s := NewStats()
+
+	s.RecordPhase("https://example.com/articles/1", PhaseFirstByte, 20*time.Millisecond)
+	s.RecordPhase("https://example.com/articles/2", PhaseFirstByte, 40*time.Millisecond)
+	s.RecordPhase("https://example.com/articles/2", PhaseParse, time.Millisecond)
+	s.RecordPhase("https://cdn.example.com/logo.png", PhaseDNS, 5*time.Millisecond)
+
+	hosts := s.HostTimings()
+
+	if len(hosts) != 2 || hosts[0].Key != "cdn.example.com" || hosts[1].Key != "example.com" {
+		t.Fatalf("unexpected host timings %+v", hosts)
+	}
+
+	// Phases are summarized in order
+	phases := hosts[1].Phases
+
+	if len(phases) != 2 || phases[0].Phase != PhaseFirstByte || phases[0].Count != 2 || phases[1].Phase != PhaseParse {
+		t.Fatalf("unexpected phases %+v", phases)
+	}
+
+	if len(phases[0].Quantiles) != len(Quantiles) || phases[0].Quantiles[2] > 40*time.Millisecond {
+		t.Fatalf("unexpected first byte quantiles %v", phases[0].Quantiles)
+	}
+
+	if patterns := s.PatternTimings(); len(patterns) != 2 || patterns[1].Key != "example.com/articles/*" {
+		t.Fatalf("unexpected pattern timings %+v", patterns)
+	}
+
+	if !strings.HasPrefix(hosts[1].String(), "example.com: first_byte p50=") {
+		t.Fatalf("unexpected summary %v", hosts[1])
+	}
+}
+
+func TestFailureReport(t *testing.T) {
+	s := NewStats()
+
+	failures := []Failure{
+		{Category: "http_4xx", URL: "https://example.com/b", Referrer: "https://example.com", StatusCode: 410},
+		{Category: "dns", URL: "https://gone.example.com"},
+		{Category: "http_4xx", URL: "https://example.com/a", Referrer: "https://example.com", StatusCode: 404},
+		{Category: "http_4xx", URL: "https://example.com/c", StatusCode: 404},
+	}
+
+	for _, failure := range failures {
+		s.RecordFailure(failure)
+	}
+
+	if categories := s.FailuresByCategory(); categories["http_4xx"] != 3 || categories["dns"] != 1 {
+		t.Fatalf("unexpected failures by category %v", categories)
+	}
+
+	if codes := s.FailuresByStatusCode(); len(codes) != 2 || codes[404] != 2 || codes[410] != 1 {
+		t.Fatalf("unexpected failures by status code %v", codes)
+	}
+
+	var b bytes.Buffer
+
+	if err := s.FailureReport().Write(&b); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	// The largest category comes first, with its URLs sorted by status code
+	expected := "🥞 Failed URLs by category:\n" +
+		"\t http_4xx (3):\n" +
+		"\t\t 404 https://example.com/a (found on https://example.com)\n" +
+		"\t\t 404 https://example.com/c\n" +
+		"\t\t 410 https://example.com/b (found on https://example.com)\n" +
+		"\t dns (1):\n" +
+		"\t\t https://gone.example.com\n"
+
+	if b.String() != expected {
+		t.Fatalf("expected report:\n%v\ngot:\n%v", expected, b.String())
+	}
+}
+
+func TestFailureReportSample(t *testing.T) {
+	s := NewStats()
+
+	for i := 0; i < failedURLsPerCategory+5; i++ {
+		s.RecordFailure(Failure{Category: "http_5xx", URL: fmt.Sprintf("https://example.com/%03d", i), StatusCode: 503})
+	}
+
+	s.RecordFailure(Failure{Category: "dns", URL: "https://gone.example.com"})
+
+	// Every failure is counted, but only the first URLs in each category are kept
+	if categories := s.FailuresByCategory(); categories["http_5xx"] != failedURLsPerCategory+5 || categories["dns"] != 1 {
+		t.Fatalf("unexpected failures by category %v", categories)
+	}
+
+	if codes := s.FailuresByStatusCode(); codes[503] != failedURLsPerCategory+5 {
+		t.Fatalf("unexpected failures by status code %v", codes)
+	}
+
+	if failed := s.FailedURLs(); len(failed) != failedURLsPerCategory+1 || failed[len(failed)-1].Category != "dns" {
+		t.Fatalf("expected %v failed urls to be kept, got %v", failedURLsPerCategory+1, len(failed))
+	}
+
+	report := s.FailureReport()
+
+	if len(report.Groups) != 2 || report.Groups[0].Count != failedURLsPerCategory+5 || len(report.Groups[0].Failures) != failedURLsPerCategory {
+		t.Fatalf("unexpected report %+v", report.Groups)
+	}
+
+	var b bytes.Buffer
+
+	if err := report.Write(&b); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	if !strings.Contains(b.String(), fmt.Sprintf("\t http_5xx (%v):\n", failedURLsPerCategory+5)) || !strings.Contains(b.String(), "\t\t ... and 5 more\n") {
+		t.Fatalf("expected the report to count the urls that weren't kept, got:\n%v", b.String())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := NewStats()
+
+	s.RecordStartTime()
+	s.RecordNewOperation()
+	s.RecordNewOperation()
+	s.RecordOperationCompletion()
+	s.RecordPage()
+	s.RecordBytes(1024)
+	s.RecordResponse(200, 10*time.Millisecond)
+	s.RecordOperationFailure()
+	s.RecordFailure(Failure{Category: "http_5xx", URL: "https://example.com/a", StatusCode: 503})
+	s.RecordTotalDuration()
+
+	snapshot := s.Snapshot()
+
+	if snapshot.Total != 2 || snapshot.Pending != 0 || snapshot.Completed != 1 || snapshot.Failures != 1 || snapshot.Pages != 1 || snapshot.Bytes != 1024 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+
+	if snapshot.ElapsedSeconds != s.Duration().Seconds() || snapshot.Latency.Count != 1 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+
+	var b bytes.Buffer
+
+	if err := snapshot.WriteJSON(&b); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	var decoded map[string]interface{}
+
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if decoded["failures_by_category"].(map[string]interface{})["http_5xx"] != 1.0 {
+		t.Fatalf("unexpected failures by category %v", decoded["failures_by_category"])
+	}
+
+	if decoded["failures_by_status_code"].(map[string]interface{})["503"] != 1.0 || decoded["status_codes"].(map[string]interface{})["200"] != 1.0 {
+		t.Fatalf("unexpected status codes in %v", b.String())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	s := NewStats()
+	s.RecordStartTime()
+
+	recorder := NewRecorder(s, 10*time.Millisecond)
+	recorder.Start()
+
+	for i := 0; i < 5; i++ {
+		s.RecordNewOperation()
+		s.RecordPage()
+		s.RecordOperationCompletion()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.RecordTotalDuration()
+	recorder.Stop()
+
+	series := recorder.Series()
+
+	// The series starts with a snapshot taken when the recorder started, and ends with one taken when it stopped
+	if len(series) < 3 || series[0].Pages != 0 || series[len(series)-1].Pages != 5 {
+		t.Fatalf("unexpected series of %v snapshots", len(series))
+	}
+
+	for i := 1; i < len(series); i++ {
+		if series[i].At.Before(series[i-1].At) || series[i].Pages < series[i-1].Pages {
+			t.Fatalf("expected snapshots to be in order, got %+v before %+v", series[i-1], series[i])
+		}
+	}
+
+	var b bytes.Buffer
+
+	if err := recorder.WriteJSON(&b); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	var decoded struct {
+		IntervalSeconds float64    `json:"interval_seconds"`
+		Final           Snapshot   `json:"final"`
+		Series          []Snapshot `json:"series"`
+	}
+
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	if decoded.IntervalSeconds != 0.01 || decoded.Final.Completed != 5 || len(decoded.Series) != len(series) {
+		t.Fatalf("unexpected recording %v", b.String())
+	}
+}
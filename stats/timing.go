@@ -0,0 +1,248 @@
+package stats
+
+import (
+	"fmt"
+	"log"
+	netUrl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Phase is a phase of fetching and processing a URL
+type Phase string
+
+const (
+	// PhaseDNS is looking up the address of the URL's host
+	PhaseDNS Phase = "dns"
+
+	// PhaseConnect is opening a TCP connection to the URL's host
+	PhaseConnect Phase = "connect"
+
+	// PhaseTLS is the TLS handshake with the URL's host
+	PhaseTLS Phase = "tls"
+
+	// PhaseFirstByte is waiting for the server to start responding after the request was written
+	PhaseFirstByte Phase = "first_byte"
+
+	// PhaseDownload is reading the response body
+	PhaseDownload Phase = "download"
+
+	// PhaseParse is parsing the page for links
+	PhaseParse Phase = "parse"
+)
+
+// Phases are every phase of fetching and processing a URL, in order
+var Phases = []Phase{PhaseDNS, PhaseConnect, PhaseTLS, PhaseFirstByte, PhaseDownload, PhaseParse}
+
+// Quantiles are the quantiles timing summaries report
+var Quantiles = []float64{0.5, 0.9, 0.99}
+
+// phaseHistograms holds a histogram of durations for each phase, keyed by phase
+type phaseHistograms map[Phase]*Histogram
+
+// observe records the duration of a phase, creating its histogram if it doesn't exist
+func (p phaseHistograms) observe(phase Phase, d time.Duration) {
+	h, exists := p[phase]
+
+	if !exists {
+		h = NewHistogram(DefaultLatencyBuckets)
+		p[phase] = h
+	}
+
+	h.Observe(d)
+}
+
+// timings holds histograms of the duration of each phase, overall,
+// for each host and for each URL pattern
+type timings struct {
+	phases   phaseHistograms
+	hosts    map[string]phaseHistograms
+	patterns map[string]phaseHistograms
+	mu       sync.Mutex
+}
+
+func newTimings() *timings {
+	return &timings{
+		phases:   make(phaseHistograms),
+		hosts:    make(map[string]phaseHistograms),
+		patterns: make(map[string]phaseHistograms),
+	}
+}
+
+// observe records the duration of a phase for a URL
+func (t *timings) observe(url string, phase Phase, d time.Duration) {
+	host, pattern := hostOf(url), URLPattern(url)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.hosts[host] == nil {
+		t.hosts[host] = make(phaseHistograms)
+	}
+
+	if t.patterns[pattern] == nil {
+		t.patterns[pattern] = make(phaseHistograms)
+	}
+
+	t.phases.observe(phase, d)
+	t.hosts[host].observe(phase, d)
+	t.patterns[pattern].observe(phase, d)
+}
+
+// phase returns the histogram of the duration of a phase for every URL
+func (t *timings) phase(phase Phase) *Histogram {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if h, exists := t.phases[phase]; exists {
+		return h
+	}
+
+	return NewHistogram(DefaultLatencyBuckets)
+}
+
+// summarize returns summaries of a group of phase histograms, sorted by key
+func (t *timings) summarize(group map[string]phaseHistograms) []TimingSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summaries := make([]TimingSummary, 0, len(group))
+
+	for key, histograms := range group {
+		summary := TimingSummary{Key: key, Phases: []PhaseSummary{}}
+
+		for _, phase := range Phases {
+			h, exists := histograms[phase]
+
+			if !exists {
+				continue
+			}
+
+			phaseSummary := PhaseSummary{Phase: phase, Count: h.Count(), Quantiles: []time.Duration{}}
+
+			for _, q := range Quantiles {
+				phaseSummary.Quantiles = append(phaseSummary.Quantiles, h.Quantile(q))
+			}
+
+			summary.Phases = append(summary.Phases, phaseSummary)
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Key < summaries[j].Key
+	})
+
+	return summaries
+}
+
+// PhaseSummary summarizes the durations of a phase
+type PhaseSummary struct {
+	// Phase is the phase that was timed
+	Phase Phase
+
+	// Count is the number of times the phase was timed
+	Count int64
+
+	// Quantiles are estimates of the durations of the phase at each of the Quantiles e.g p50, p90 and p99
+	Quantiles []time.Duration
+}
+
+// TimingSummary summarizes the durations of each phase for a host or URL pattern
+type TimingSummary struct {
+	// Key is the host or URL pattern
+	Key string
+
+	// Phases are the summaries of the phases that were timed, in order
+	Phases []PhaseSummary
+}
+
+// String describes the summary e.g "example.com: dns p50=2ms p90=5ms p99=9ms, ..."
+func (t TimingSummary) String() string {
+	phases := []string{}
+
+	for _, phase := range t.Phases {
+		quantiles := []string{}
+
+		for i, q := range Quantiles {
+			quantiles = append(quantiles, fmt.Sprintf("p%v=%v", q*100, phase.Quantiles[i].Round(10*time.Microsecond)))
+		}
+
+		phases = append(phases, fmt.Sprintf("%v %v", phase.Phase, strings.Join(quantiles, " ")))
+	}
+
+	return fmt.Sprintf("%v: %v", t.Key, strings.Join(phases, ", "))
+}
+
+// hostOf returns the host of a URL, or the URL itself if it can't be parsed
+func hostOf(url string) string {
+	parsedURL, err := netUrl.Parse(url)
+
+	if err != nil {
+		return url
+	}
+
+	return parsedURL.Host
+}
+
+// URLPattern returns the pattern a URL's timings are grouped under: its host and the first
+// segment of its path, followed by /* if the path is longer. Segments with digits in them
+// are usually IDs or dates, so they are replaced with :id
+// e.g https://example.com/articles/2021/hello becomes example.com/articles/*
+func URLPattern(url string) string {
+	parsedURL, err := netUrl.Parse(url)
+
+	if err != nil {
+		return url
+	}
+
+	segments := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	pattern := parsedURL.Host + "/"
+
+	if segments[0] == "" {
+		return pattern
+	}
+
+	if strings.IndexFunc(segments[0], unicode.IsDigit) >= 0 {
+		pattern += ":id"
+	} else {
+		pattern += segments[0]
+	}
+
+	if len(segments) > 1 {
+		pattern += "/*"
+	}
+
+	return pattern
+}
+
+// RecordPhase records how long a phase of fetching or processing a URL took
+func (s *Stats) RecordPhase(url string, phase Phase, d time.Duration) {
+	s.timings.observe(url, phase, d)
+}
+
+// HostTimings returns a summary of the duration of each phase for each host, sorted by host
+func (s *Stats) HostTimings() []TimingSummary {
+	return s.timings.summarize(s.timings.hosts)
+}
+
+// PatternTimings returns a summary of the duration of each phase for each URL pattern,
+// sorted by pattern. See URLPattern for how URLs are grouped
+func (s *Stats) PatternTimings() []TimingSummary {
+	return s.timings.summarize(s.timings.patterns)
+}
+
+// PrintTimings prints out the duration of each phase for each host and URL pattern
+func (s *Stats) PrintTimings() {
+	for _, summary := range s.HostTimings() {
+		log.Printf("⏱ %v", summary)
+	}
+
+	for _, summary := range s.PatternTimings() {
+		log.Printf("⏱ %v", summary)
+	}
+}
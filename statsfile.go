@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/darthchudi/crwl/stats"
+	"os"
+)
+
+// saveStats saves the snapshots of a crawler's stats taken by a recorder to a file as JSON
+func saveStats(path string, recorder *stats.Recorder) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	err = recorder.WriteJSON(file)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
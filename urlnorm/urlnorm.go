@@ -0,0 +1,178 @@
+// urlnorm resolves links against the pages they are found on and
+// normalizes URLs, so that different spellings of the same URL
+// are stored once
+package urlnorm
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	netUrl "net/url"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedScheme is returned for URLs that can't be crawled
+// e.g `mailto:`, `tel:` and `javascript:` links
+var ErrUnsupportedScheme = errors.New("unsupported url scheme")
+
+// defaultPorts maps supported schemes to their default ports
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Options configures how URLs are normalized.
+// The zero value is ready to use
+type Options struct {
+	// SortQuery sorts query parameters by name
+	SortQuery bool
+
+	// StripQuery lists query parameters to remove. Names ending with `*`
+	// remove every parameter with that prefix e.g "utm_*"
+	StripQuery []string
+
+	// KeepTrailingSlash keeps the trailing slash of paths, which is removed by default
+	KeepTrailingSlash bool
+}
+
+// Normalize normalizes an absolute http or https URL.
+//
+// The scheme and host are lowercased, internationalized hosts are converted
+// to punycode, default ports, fragments and dot segments are removed and
+// query parameters are stripped and sorted according to the options.
+func Normalize(url string, opts Options) (string, error) {
+	parsedURL, err := netUrl.Parse(strings.TrimSpace(url))
+
+	if err != nil {
+		return "", err
+	}
+
+	return normalize(parsedURL, opts)
+}
+
+// Resolve resolves a link found on a page against the page's URL and normalizes it.
+// Relative (`foo.html`, `../bar`), root-relative (`/x`) and protocol-relative
+// (`//host/x`) links are supported.
+func Resolve(base, link string, opts Options) (string, error) {
+	parsedBase, err := netUrl.Parse(strings.TrimSpace(base))
+
+	if err != nil {
+		return "", err
+	}
+
+	parsedLink, err := netUrl.Parse(strings.TrimSpace(link))
+
+	if err != nil {
+		return "", err
+	}
+
+	return normalize(parsedBase.ResolveReference(parsedLink), opts)
+}
+
+// normalize normalizes a parsed URL
+func normalize(u *netUrl.URL, opts Options) (string, error) {
+	scheme := strings.ToLower(u.Scheme)
+	defaultPort, supported := defaultPorts[scheme]
+
+	if !supported {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("url %v has no host", u)
+	}
+
+	host, err := idna.ToASCII(strings.ToLower(u.Hostname()))
+
+	if err != nil {
+		return "", err
+	}
+
+	// Wrap IPv6 addresses in brackets
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	if port := u.Port(); port != "" && port != defaultPort {
+		host += ":" + port
+	}
+
+	normalized := &netUrl.URL{
+		Scheme: scheme,
+		User:   u.User,
+		Host:   host,
+	}
+
+	// Resolving an empty reference removes dot segments from the path
+	u = u.ResolveReference(&netUrl.URL{})
+	normalized.Path = u.Path
+	normalized.RawPath = u.RawPath
+
+	if !opts.KeepTrailingSlash {
+		normalized.Path = strings.TrimSuffix(normalized.Path, "/")
+		normalized.RawPath = strings.TrimSuffix(normalized.RawPath, "/")
+	} else if normalized.Path == "" {
+		normalized.Path = "/"
+	}
+
+	normalized.RawQuery = normalizeQuery(u.RawQuery, opts)
+
+	return normalized.String(), nil
+}
+
+// normalizeQuery strips and sorts the parameters of a raw query string.
+// Parameters are otherwise left as they are.
+func normalizeQuery(query string, opts Options) string {
+	if query == "" {
+		return ""
+	}
+
+	params := []string{}
+
+	for _, param := range strings.Split(query, "&") {
+		if param == "" || stripped(paramName(param), opts.StripQuery) {
+			continue
+		}
+
+		params = append(params, param)
+	}
+
+	if opts.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return paramName(params[i]) < paramName(params[j])
+		})
+	}
+
+	return strings.Join(params, "&")
+}
+
+// paramName returns the unescaped name of a query parameter e.g "q" for "q=loans"
+func paramName(param string) string {
+	name := param
+
+	if i := strings.Index(param, "="); i >= 0 {
+		name = param[:i]
+	}
+
+	if unescaped, err := netUrl.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+
+	return name
+}
+
+// stripped checks if a query parameter should be removed
+func stripped(name string, strip []string) bool {
+	for _, pattern := range strip {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, pattern[:len(pattern)-1]) {
+			return true
+		}
+
+		if name == pattern {
+			return true
+		}
+	}
+
+	return false
+}
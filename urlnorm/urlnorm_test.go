@@ -0,0 +1,92 @@
+package urlnorm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  Options
+		want  string
+	}{
+		{input: "HTTPS://Example.COM/About", want: "https://example.com/About"},
+		{input: "https://example.com/", want: "https://example.com"},
+		{input: "https://example.com", opts: Options{KeepTrailingSlash: true}, want: "https://example.com/"},
+		{input: "https://example.com/help/", want: "https://example.com/help"},
+		{input: "https://example.com/help/", opts: Options{KeepTrailingSlash: true}, want: "https://example.com/help/"},
+		{input: "https://example.com:443/cards", want: "https://example.com/cards"},
+		{input: "http://example.com:80/cards", want: "http://example.com/cards"},
+		{input: "http://example.com:8080/cards", want: "http://example.com:8080/cards"},
+		{input: "https://example.com/cards#pricing", want: "https://example.com/cards"},
+		{input: "https://example.com/a/./b/../c", want: "https://example.com/a/c"},
+		{input: "https://münchen.de/karten", want: "https://xn--mnchen-3ya.de/karten"},
+		{input: "https://[::1]:443/cards", want: "https://[::1]/cards"},
+		{input: "https://example.com/search?q=loans&utm_source=mail&page=2", opts: Options{StripQuery: []string{"utm_*"}}, want: "https://example.com/search?q=loans&page=2"},
+		{input: "https://example.com/search?q=loans&page=2", opts: Options{SortQuery: true}, want: "https://example.com/search?page=2&q=loans"},
+		{input: "https://example.com/search?utm_source=mail&fbclid=1", opts: Options{StripQuery: []string{"utm_*", "fbclid"}}, want: "https://example.com/search"},
+	}
+
+	for _, tc := range tests {
+		got, err := Normalize(tc.input, tc.opts)
+
+		if err != nil {
+			t.Errorf("normalize %v error: %v", tc.input, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("expected %v to normalize to %v, got %v", tc.input, tc.want, got)
+		}
+
+		// Normalizing a normalized URL doesn't change it
+		again, err := Normalize(got, tc.opts)
+
+		if err != nil || again != got {
+			t.Errorf("expected %v to be normalized, got %v (error: %v)", got, again, err)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	base := "https://example.com/loans/personal.html"
+
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "/cards", want: "https://example.com/cards"},
+		{link: "rates.html", want: "https://example.com/loans/rates.html"},
+		{link: "../business/", want: "https://example.com/business"},
+		{link: "//community.example.com/x", want: "https://community.example.com/x"},
+		{link: "?page=2", want: "https://example.com/loans/personal.html?page=2"},
+		{link: "#apply", want: "https://example.com/loans/personal.html"},
+		{link: " https://Twitter.com/brand ", want: "https://twitter.com/brand"},
+	}
+
+	for _, tc := range tests {
+		got, err := Resolve(base, tc.link, Options{})
+
+		if err != nil {
+			t.Errorf("resolve %v error: %v", tc.link, err)
+			continue
+		}
+
+		if got != tc.want {
+			t.Errorf("expected %v to resolve to %v, got %v", tc.link, tc.want, got)
+		}
+	}
+}
+
+func TestResolveUnsupportedScheme(t *testing.T) {
+	links := []string{"mailto:hello@example.com", "tel:+2348000000000", "javascript:void(0)", "ftp://example.com/file"}
+
+	for _, link := range links {
+		_, err := Resolve("https://example.com", link, Options{})
+
+		if !errors.Is(err, ErrUnsupportedScheme) {
+			t.Errorf("expected %v to have an unsupported scheme, got %v", link, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// readURLs reads a list of URLs from a sitemap, or from a text file with one URL per line.
+// Blank lines and lines starting with # are ignored in text files
+func readURLs(r io.Reader) ([]string, error) {
+	data, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return readSitemap(bytes.NewReader(data))
+	}
+
+	urls := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// readSitemap reads the URLs in the <loc> elements of a sitemap
+func readSitemap(r io.Reader) ([]string, error) {
+	decoder := xml.NewDecoder(r)
+	urls := []string{}
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			return urls, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		element, ok := token.(xml.StartElement)
+
+		if !ok || element.Name.Local != "loc" {
+			continue
+		}
+
+		var loc string
+
+		if err := decoder.DecodeElement(&loc, &element); err != nil {
+			return nil, err
+		}
+
+		if loc = strings.TrimSpace(loc); loc != "" {
+			urls = append(urls, loc)
+		}
+	}
+}